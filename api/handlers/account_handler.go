@@ -2,23 +2,30 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/ashil-poojary/banking-ledger-service/api/middleware"
+	"github.com/ashil-poojary/banking-ledger-service/ledger"
 	"github.com/ashil-poojary/banking-ledger-service/models"
 	"github.com/ashil-poojary/banking-ledger-service/utils"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
 // AccountHandler handles account-related requests
 type AccountHandler struct {
-	DB *gorm.DB
+	DB      *gorm.DB
+	FXRates ledger.FXRateProvider // Converts balances swept into an account in another currency
 }
 
 // NewAccountHandler initializes a new AccountHandler
-func NewAccountHandler(db *gorm.DB) *AccountHandler {
-	return &AccountHandler{DB: db}
+func NewAccountHandler(db *gorm.DB, fxRates ledger.FXRateProvider) *AccountHandler {
+	return &AccountHandler{DB: db, FXRates: fxRates}
 }
 
 // CreateAccount handles account creation
@@ -35,8 +42,17 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The balance is a projection of the journal, so money only comes in
+	// through deposits and an account always opens empty
+	if !account.Balance.IsZero() {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Accounts open with a zero balance; make a deposit to fund it", nil, "")
+		return
+	}
+
 	account.UserID = userID
 	account.ID = uuid.New().String()
+	account.Status = models.AccountActive
+	account.Balance = models.NewMoney(0, account.Currency)
 
 	// Validate account fields before inserting
 	if err := account.Validate(); err != nil {
@@ -75,9 +91,41 @@ func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", account.ETag())
 	utils.SendResponse(w, http.StatusOK, true, "Account retrieved successfully", account, "")
 }
 
+// GetBalance returns the balance of an account from every posting made
+// before as_of (default now)
+func (h *AccountHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	accountNumber := mux.Vars(r)["number"]
+
+	asOf := time.Now().UTC()
+	if t, err := models.ParseTime(r.URL.Query().Get("as_of")); err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Invalid as_of: "+err.Error())
+		return
+	} else if t != nil {
+		asOf = *t
+	}
+
+	balance, err := ledger.BalanceAt(h.DB, accountNumber, asOf)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.SendResponse(w, http.StatusNotFound, false, "Account not found", nil, "")
+		return
+	}
+	if err != nil {
+		log.Println("Failed to compute balance:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to compute balance", nil, err.Error())
+		return
+	}
+
+	utils.SendResponse(w, http.StatusOK, true, "Balance retrieved successfully", map[string]interface{}{
+		"account_number": accountNumber,
+		"as_of":          asOf,
+		"balance":        balance,
+	}, "")
+}
+
 func (h *AccountHandler) GetUserAccounts(w http.ResponseWriter, r *http.Request) {
 	userID, err := utils.ExtractUserID(r)
 	if err != nil {
@@ -94,45 +142,82 @@ func (h *AccountHandler) GetUserAccounts(w http.ResponseWriter, r *http.Request)
 	utils.SendResponse(w, http.StatusOK, true, "Accounts retrieved successfully", accounts, "")
 }
 
-// UpdateAccount updates a specific account belonging to the authenticated user
+// UpdateAccount applies a JSON Merge Patch of the customer-editable fields
+// (owner_name, account_type, nickname) to the account named in the path. An
+// If-Match header must match the account's current ETag; either way a
+// concurrent change between reading and writing the account is refused.
 func (h *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
-	userID, err := utils.ExtractUserID(r)
-	if err != nil {
-		utils.SendResponse(w, http.StatusUnauthorized, false, "Unauthorized", nil, err.Error())
-		return
-	}
+	h.patchAccount(w, r, mux.Vars(r)["number"])
+}
 
+// UpdateAccountByQuery is the legacy PUT /update-account?account_number=
+// form of UpdateAccount. It accepts the same fields with the same rules.
+func (h *AccountHandler) UpdateAccountByQuery(w http.ResponseWriter, r *http.Request) {
 	accountNumber := r.URL.Query().Get("account_number")
 	if accountNumber == "" {
 		utils.SendResponse(w, http.StatusBadRequest, false, "Account number is required", nil, "")
 		return
 	}
+	h.patchAccount(w, r, accountNumber)
+}
 
-	var updateData models.Account
-	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
+func (h *AccountHandler) patchAccount(w http.ResponseWriter, r *http.Request, accountNumber string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request payload", nil, err.Error())
+		return
+	}
+	patch, err := models.ParseAccountPatch(body)
+	if err != nil {
 		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request payload", nil, err.Error())
 		return
 	}
 
-	// Ensure the account belongs to the user and update it
-	result := h.DB.Model(&models.Account{}).
-		Where("account_number = ? AND user_id = ?", accountNumber, userID).
-		Updates(updateData)
+	// Ownership is checked by the route
+	var account models.Account
+	if err := h.DB.Where("account_number = ?", accountNumber).First(&account).Error; err != nil {
+		utils.SendResponse(w, http.StatusNotFound, false, "Account not found", nil, "")
+		return
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" && ifMatch != account.ETag() {
+		w.Header().Set("ETag", account.ETag())
+		utils.SendResponse(w, http.StatusPreconditionFailed, false, "Account has changed; fetch it again and retry", nil, "")
+		return
+	}
 
-	if result.Error != nil {
-		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to update account", nil, result.Error.Error())
+	if err := patch.Apply(&account); err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid account details", nil, err.Error())
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		utils.SendResponse(w, http.StatusNotFound, false, "Account not found or no changes applied", nil, "")
+	// Write only the whitelisted fields, and only if nobody else changed the account meanwhile
+	err = ledger.UpdateAccount(h.DB, &account, map[string]interface{}{
+		"owner_name":   account.OwnerName,
+		"account_type": account.AccountType,
+		"nickname":     account.Nickname,
+		"updated_at":   time.Now(),
+	})
+	if errors.Is(err, ledger.ErrVersionConflict) {
+		utils.SendResponse(w, http.StatusConflict, false, "Account was modified concurrently; fetch it again and retry", nil, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to update account", nil, err.Error())
 		return
 	}
 
-	utils.SendResponse(w, http.StatusOK, true, "Account updated successfully", updateData, "")
+	// Respond with the account as persisted
+	if err := h.DB.Where("account_number = ?", accountNumber).First(&account).Error; err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to load account", nil, err.Error())
+		return
+	}
+	w.Header().Set("ETag", account.ETag())
+	utils.SendResponse(w, http.StatusOK, true, "Account updated successfully", account, "")
 }
 
-// DeleteAccount deletes a specific account belonging to the authenticated user
+// DeleteAccount closes a specific account belonging to the authenticated
+// user. The balance must be zero unless sweep_to names another of the user's
+// accounts to receive it. The account is soft deleted so its history is kept.
 func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	userID, err := utils.ExtractUserID(r)
 	if err != nil {
@@ -146,18 +231,57 @@ func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Ensure the account belongs to the user before deleting
-	result := h.DB.Where("account_number = ? AND user_id = ?", accountNumber, userID).Delete(&models.Account{})
-
-	if result.Error != nil {
-		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to delete account", nil, result.Error.Error())
-		return
+	// The balance may only be swept to another account of the same user
+	sweepTo := r.URL.Query().Get("sweep_to")
+	if sweepTo != "" {
+		owned, err := middleware.OwnsAccounts(h.DB, userID, sweepTo)
+		if err != nil {
+			utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to close account", nil, err.Error())
+			return
+		}
+		if !owned || sweepTo == accountNumber {
+			utils.SendResponse(w, http.StatusBadRequest, false, "Sweep account not found", nil, "")
+			return
+		}
 	}
 
-	if result.RowsAffected == 0 {
+	var account *models.Account
+	var sweep *models.JournalEntry
+	err = ledger.RunInTransaction(h.DB, func(tx *gorm.DB) error {
+		var err error
+		account, sweep, err = ledger.CloseAccount(tx, h.FXRates, accountNumber, sweepTo, "closed by owner", userID)
+		return err
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, ledger.ErrAccountNotFound):
 		utils.SendResponse(w, http.StatusNotFound, false, "Account not found", nil, "")
 		return
+	case errors.Is(err, ledger.ErrNonZeroBalance):
+		utils.SendResponse(w, http.StatusConflict, false, "Account balance must be zero; pass sweep_to to move it to another account", nil, "")
+		return
+	case errors.Is(err, models.ErrIllegalAccountTransition):
+		utils.SendResponse(w, http.StatusConflict, false, "Account cannot be closed in its current status", nil, "")
+		return
+	case errors.Is(err, ledger.ErrVersionConflict):
+		utils.SendResponse(w, http.StatusConflict, false, "Account was modified concurrently; retry", nil, "")
+		return
+	case errors.Is(err, ledger.ErrDestinationNotFound), errors.Is(err, ledger.ErrDestinationClosed):
+		utils.SendResponse(w, http.StatusBadRequest, false, "Sweep account not found", nil, "")
+		return
+	case errors.Is(err, ledger.ErrConversion):
+		utils.SendResponse(w, http.StatusBadRequest, false, "Currency conversion is not available for this sweep", nil, "")
+		return
+	default:
+		log.Println("Failed to close account:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to close account", nil, err.Error())
+		return
 	}
 
-	utils.SendResponse(w, http.StatusOK, true, "Account deleted successfully", nil, "")
+	data := map[string]interface{}{"account": account}
+	if sweep != nil {
+		data["sweep_entry_id"] = sweep.ID
+	}
+	utils.SendResponse(w, http.StatusOK, true, "Account closed successfully", data, "")
 }
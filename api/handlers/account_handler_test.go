@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestCreateAccountBalance tests that accounts cannot be opened with money
+// that never came in through a deposit
+func TestCreateAccountBalance(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Account{}, &models.JournalEntry{}, &models.Posting{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	utils.SetKeyManager(utils.NewHMACKeyManager("test-secret"))
+	token, err := utils.GenerateJWT("alice", models.RoleCustomer, "session-1", false)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	handler := NewAccountHandler(db, nil)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		// ✅ Opens empty
+		{"No balance", `{"owner_name":"Alice","account_type":"Savings","currency":"USD"}`, http.StatusCreated},
+		{"Zero balance", `{"owner_name":"Alice","account_type":"Savings","currency":"USD","balance":{"value":"0","currency":"USD"}}`, http.StatusCreated},
+		// ❌ Money out of thin air
+		{"Opening balance", `{"owner_name":"Alice","account_type":"Savings","currency":"USD","balance":{"value":"1000000.00","currency":"USD"}}`, http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			req := httptest.NewRequest("POST", "/api/create-account", strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.CreateAccount(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.expectedStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// Nothing was posted to the journal
+	var entries int64
+	db.Model(&models.JournalEntry{}).Count(&entries)
+	if entries != 0 {
+		t.Errorf("Expected no journal entries, got %d", entries)
+	}
+}
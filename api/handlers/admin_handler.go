@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ashil-poojary/banking-ledger-service/api/middleware"
+	"github.com/ashil-poojary/banking-ledger-service/ledger"
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/session"
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+	"github.com/ashil-poojary/banking-ledger-service/worker"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// Search results are capped so a broad query cannot dump every account
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+// AdminHandler handles staff-only requests that act across users
+type AdminHandler struct {
+	PostgresDB *gorm.DB
+	MongoDB    *mongo.Database
+	Sessions   *session.Store
+	Denylist   *session.Denylist
+}
+
+// NewAdminHandler initializes a new AdminHandler
+func NewAdminHandler(postgresDB *gorm.DB, mongoDB *mongo.Database, sessions *session.Store, denylist *session.Denylist) *AdminHandler {
+	return &AdminHandler{PostgresDB: postgresDB, MongoDB: mongoDB, Sessions: sessions, Denylist: denylist}
+}
+
+// SearchAccounts finds accounts of any user by owner name or account number
+// (q), user ID or status
+func (h *AdminHandler) SearchAccounts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	db := h.PostgresDB.Model(&models.Account{})
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		pattern := "%" + escapeLike(strings.ToLower(q)) + "%"
+		db = db.Where(`LOWER(owner_name) LIKE ? ESCAPE '\' OR account_number LIKE ? ESCAPE '\'`, pattern, pattern)
+	}
+	if userID := query.Get("user_id"); userID != "" {
+		db = db.Where("user_id = ?", userID)
+	}
+	if status := query.Get("status"); status != "" {
+		db = db.Where("status = ?", status)
+		if status == models.AccountClosed {
+			db = db.Unscoped() // Closed accounts are soft deleted
+		}
+	}
+
+	var accounts []models.Account
+	if err := db.Order("created_at DESC").Limit(limit).Find(&accounts).Error; err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to search accounts", nil, err.Error())
+		return
+	}
+
+	utils.SendResponse(w, http.StatusOK, true, "Accounts retrieved successfully", accounts, "")
+}
+
+// likeEscaper escapes the LIKE wildcards so a search matches them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes s for use in a LIKE pattern with ESCAPE '\'
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// FreezeAccount stops any money leaving an account
+func (h *AdminHandler) FreezeAccount(w http.ResponseWriter, r *http.Request) {
+	h.changeAccountStatus(w, r, models.AccountFrozen, statusReason(r), "Account frozen successfully")
+}
+
+// UnfreezeAccount lifts a freeze
+func (h *AdminHandler) UnfreezeAccount(w http.ResponseWriter, r *http.Request) {
+	h.changeAccountStatus(w, r, models.AccountActive, statusReason(r), "Account unfrozen successfully")
+}
+
+// SetAccountStatus moves an account to any status its current one allows,
+// e.g. marking it dormant, reactivating it or closing it at zero balance
+func (h *AdminHandler) SetAccountStatus(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !models.IsValidAccountStatus(req.Status) {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Status must be one of active, frozen, dormant or closed")
+		return
+	}
+	h.changeAccountStatus(w, r, req.Status, req.Reason, "Account status updated successfully")
+}
+
+// GetAccountStatusHistory lists the audit records of an account's status changes
+func (h *AdminHandler) GetAccountStatusHistory(w http.ResponseWriter, r *http.Request) {
+	var changes []models.AccountStatusChange
+	err := h.PostgresDB.Where("account_number = ?", mux.Vars(r)["number"]).Order("created_at, id").Find(&changes).Error
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to retrieve status history", nil, err.Error())
+		return
+	}
+	utils.SendResponse(w, http.StatusOK, true, "Status history retrieved successfully", changes, "")
+}
+
+// statusReason reads the optional reason for a freeze or unfreeze
+func statusReason(r *http.Request) string {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	return req.Reason
+}
+
+// changeAccountStatus applies a status change and records who made it
+func (h *AdminHandler) changeAccountStatus(w http.ResponseWriter, r *http.Request, status, reason, message string) {
+	accountNumber := mux.Vars(r)["number"]
+	changedBy := middleware.UserIDFromContext(r)
+
+	var account *models.Account
+	err := ledger.RunInTransaction(h.PostgresDB, func(tx *gorm.DB) error {
+		var err error
+		account, err = ledger.SetAccountStatus(tx, accountNumber, status, reason, changedBy)
+		return err
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, ledger.ErrAccountNotFound):
+		utils.SendResponse(w, http.StatusNotFound, false, "Account not found", nil, "")
+		return
+	case errors.Is(err, models.ErrIllegalAccountTransition):
+		utils.SendResponse(w, http.StatusConflict, false, "", nil, err.Error())
+		return
+	case errors.Is(err, ledger.ErrNonZeroBalance):
+		utils.SendResponse(w, http.StatusConflict, false, "", nil, "Account balance must be zero to close it")
+		return
+	case errors.Is(err, ledger.ErrVersionConflict):
+		utils.SendResponse(w, http.StatusConflict, false, "", nil, "Account was modified concurrently; retry")
+		return
+	default:
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to update account", nil, err.Error())
+		return
+	}
+
+	log.Printf("[Admin] User %s set account %s to %s", changedBy, accountNumber, status)
+	utils.SendResponse(w, http.StatusOK, true, message, account, "")
+}
+
+// ReverseTransaction undoes a completed transaction by posting the opposite
+// journal entry and marking the transaction reversed
+func (h *AdminHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Invalid transaction ID")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "A reason is required")
+		return
+	}
+
+	var txn models.Transaction
+	if err := h.MongoDB.Collection("transactions").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&txn); err != nil {
+		utils.SendResponse(w, http.StatusNotFound, false, "", nil, "Transaction not found")
+		return
+	}
+	if txn.Status != models.StatusCompleted && txn.Status != models.StatusReversed {
+		utils.SendResponse(w, http.StatusConflict, false, "", nil, "Only completed transactions can be reversed")
+		return
+	}
+
+	// Post the reversal first; it is idempotent, so a retry after a failed
+	// status update does not reverse the money twice
+	var reversal *models.JournalEntry
+	err = ledger.RunInTransaction(h.PostgresDB, func(tx *gorm.DB) error {
+		var err error
+		reversal, err = ledger.Reverse(tx, txn.Reference, req.Reason)
+		return err
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, ledger.ErrEntryNotFound):
+		utils.SendResponse(w, http.StatusConflict, false, "", nil, "Transaction has no journal entry to reverse")
+		return
+	case errors.Is(err, ledger.ErrInsufficientFunds):
+		utils.SendResponse(w, http.StatusConflict, false, "", nil, "Reversal would overdraw an account")
+		return
+	case errors.Is(err, ledger.ErrAccountClosed):
+		utils.SendResponse(w, http.StatusConflict, false, "", nil, "Reversal touches a closed account")
+		return
+	default:
+		log.Println("Failed to reverse transaction:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to reverse transaction")
+		return
+	}
+
+	reversed, err := worker.TransitionStatus(h.MongoDB, id, models.StatusReversed, req.Reason, bson.M{
+		"reversal_reference": reversal.ID,
+	})
+	if err != nil {
+		log.Println("Failed to mark transaction reversed:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to reverse transaction")
+		return
+	}
+
+	log.Printf("[Admin] User %s reversed transaction %s: %s", middleware.UserIDFromContext(r), id.Hex(), req.Reason)
+	reversed.ReversalReference = reversal.ID
+	utils.SendResponse(w, http.StatusOK, true, "Transaction reversed successfully", reversed, "")
+}
+
+// SetUserRole changes the role of a user. Roles are read from the access
+// token, so the new role applies at the user's next token refresh.
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(userID); err != nil {
+		utils.SendResponse(w, http.StatusNotFound, false, "User not found", nil, "")
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !models.IsValidRole(req.Role) {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Role must be one of customer, teller or admin")
+		return
+	}
+
+	result := h.PostgresDB.Model(&models.User{}).Where("id = ?", userID).Update("role", req.Role)
+	if result.Error != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to update user", nil, result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.SendResponse(w, http.StatusNotFound, false, "User not found", nil, "")
+		return
+	}
+
+	log.Printf("[Admin] User %s set role of %s to %s", middleware.UserIDFromContext(r), userID, req.Role)
+	utils.SendResponse(w, http.StatusOK, true, "Role updated; it applies at the user's next token refresh", map[string]string{"id": userID, "role": req.Role}, "")
+}
+
+// RevokeUserSessions signs a user out of every device, e.g. when their
+// credentials are suspected to be compromised
+func (h *AdminHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(userID); err != nil {
+		utils.SendResponse(w, http.StatusNotFound, false, "User not found", nil, "")
+		return
+	}
+
+	revoked, err := h.Sessions.RevokeAll(r.Context(), userID)
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to revoke sessions", nil, err.Error())
+		return
+	}
+
+	log.Printf("[Admin] User %s revoked %d sessions of %s", middleware.UserIDFromContext(r), revoked, userID)
+	utils.SendResponse(w, http.StatusOK, true, "Sessions revoked successfully", map[string]int{"revoked": revoked}, "")
+}
+
+// RevokeToken revokes a single access token, e.g. one that was leaked. The
+// session it belongs to stays signed in.
+func (h *AdminHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "token is required")
+		return
+	}
+
+	// Expired or forged tokens are already unusable
+	claims, err := utils.ParseAccessToken(req.Token)
+	if err != nil || claims.ID == "" {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Token is invalid or already expired")
+		return
+	}
+
+	if err := h.Denylist.Revoke(r.Context(), claims.ID, claims.ExpiresAt); err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to revoke token", nil, err.Error())
+		return
+	}
+
+	log.Printf("[Admin] User %s revoked token %s of %s", middleware.UserIDFromContext(r), claims.ID, claims.UserID)
+	utils.SendResponse(w, http.StatusOK, true, "Token revoked successfully", map[string]string{"jti": claims.ID, "user_id": claims.UserID}, "")
+}
@@ -1,47 +1,73 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ashil-poojary/banking-ledger-service/api/middleware"
+	"github.com/ashil-poojary/banking-ledger-service/auth"
 	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/session"
 	"github.com/ashil-poojary/banking-ledger-service/utils"
 	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // AuthHandler handles user authentication
 type AuthHandler struct {
-	DB    *gorm.DB
-	Redis *redis.Client
+	DB         *gorm.DB
+	Redis      *redis.Client
+	Sessions   *session.Store
+	Denylist   *session.Denylist
+	Throttle   *auth.LoginThrottle
+	Challenges *auth.MFAChallenges
 }
 
 // NewAuthHandler creates a new AuthHandler instance
-func NewAuthHandler(db *gorm.DB, redisClient *redis.Client) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, redisClient *redis.Client, sessions *session.Store, denylist *session.Denylist, throttle *auth.LoginThrottle) *AuthHandler {
 	return &AuthHandler{
-		DB:    db,
-		Redis: redisClient,
+		DB:         db,
+		Redis:      redisClient,
+		Sessions:   sessions,
+		Denylist:   denylist,
+		Throttle:   throttle,
+		Challenges: auth.NewMFAChallenges(redisClient),
 	}
 }
 
 // Register handles user registration
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	var req struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Phone    string `json:"phone"`
+		Password string `json:"password"` // Not part of models.User's JSON, so decoded here
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, err.Error())
 		return
 	}
+	if len(req.Password) < models.MinPasswordLength {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, fmt.Sprintf("password must be at least %d characters", models.MinPasswordLength))
+		return
+	}
+	user := models.User{Username: req.Username, Email: req.Email, Phone: req.Phone}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to hash password")
 		return
 	}
 	user.Password = string(hashedPassword)
+	user.Role = models.RoleCustomer // Staff roles are only granted by an admin
 
 	// Save user in DB
 	if err := h.DB.Create(&user).Error; err != nil {
@@ -52,52 +78,190 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	utils.SendResponse(w, http.StatusCreated, true, "User registered successfully", nil, "")
 }
 
-// Login handles user authentication
-func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-	var dbUser models.User
+// dummyPasswordHash is compared against when the username does not exist,
+// so unknown usernames take as long to reject as wrong passwords
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
 
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, err.Error())
+// Login handles user authentication. Failed attempts slow down further
+// attempts for the same username or IP and eventually lock them out; every
+// failure gets the same response whether or not the username exists.
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, "username and password are required")
 		return
 	}
+	ip := utils.ClientIP(r)
 
-	// Find user in DB
-	if err := h.DB.Where("username = ?", user.Username).First(&dbUser).Error; err != nil {
-		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid credentials", nil, err.Error())
+	delay, lockedFor, err := h.Throttle.Check(r.Context(), req.Username, ip)
+	if err != nil {
+		log.Println("Failed to check login throttle:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Login is temporarily unavailable", nil, "")
+		return
+	}
+	if lockedFor > 0 {
+		h.recordLoginAttempt(r, req.Username, "", models.LoginLockedOut)
+		sendLockedOut(w, lockedFor)
+		return
+	}
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
 		return
 	}
-	log.Printf("User registered")
-	log.Print(dbUser)
 
-	// Compare passwords
-	if err := bcrypt.CompareHashAndPassword([]byte(dbUser.Password), []byte(user.Password)); err != nil {
-		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid credentials", nil, err.Error())
+	// Find user in DB; compare against a dummy hash if there is none
+	var dbUser models.User
+	found := h.DB.Where("username = ?", req.Username).First(&dbUser).Error == nil
+	hash := dummyPasswordHash
+	if found {
+		hash = []byte(dbUser.Password)
+	}
+	passwordOK := bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) == nil && req.Password != ""
+
+	if !found || !passwordOK {
+		userID := ""
+		if found {
+			userID = dbUser.ID.String()
+		}
+		h.recordLoginAttempt(r, req.Username, userID, models.LoginInvalidCredentials)
+
+		locked, err := h.Throttle.Fail(r.Context(), req.Username, ip)
+		if err != nil {
+			log.Println("Failed to record failed login:", err)
+		}
+		if locked {
+			sendLockedOut(w, h.Throttle.Lockout)
+			return
+		}
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid credentials", nil, "")
 		return
 	}
 
-	// Generate JWT token with UserID
-	token, err := utils.GenerateJWT(dbUser.ID.String())
+	// With MFA on, the password only earns a challenge for the second factor
+	mfaEnabled, err := auth.Enabled(h.DB, dbUser.ID.String())
 	if err != nil {
-		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to generate token", nil, err.Error())
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Login is temporarily unavailable", nil, "")
+		return
+	}
+	if mfaEnabled {
+		challenge, err := h.Challenges.Create(r.Context(), dbUser.ID.String())
+		if err != nil {
+			log.Println("Failed to store MFA challenge:", err)
+			utils.SendResponse(w, http.StatusInternalServerError, false, "Login is temporarily unavailable", nil, "")
+			return
+		}
+		h.recordLoginAttempt(r, req.Username, dbUser.ID.String(), models.LoginMFARequired)
+		utils.SendResponse(w, http.StatusOK, true, "MFA code required", map[string]interface{}{
+			"mfa_required":    true,
+			"challenge_token": challenge,
+			"expires_in":      int(auth.ChallengeTTL.Seconds()),
+		}, "")
 		return
 	}
 
-	// Store session in Redis with UserID as key
-	ctx := context.Background()
-	err = h.Redis.Set(ctx, dbUser.ID.String(), token, 24*time.Hour).Err()
+	h.startSession(w, r, &dbUser, false)
+}
+
+// startSession completes a login: it clears failed attempts, records the
+// login and issues tokens for a new session
+func (h *AuthHandler) startSession(w http.ResponseWriter, r *http.Request, user *models.User, mfa bool) {
+	if err := h.Throttle.Succeed(r.Context(), user.Username); err != nil {
+		log.Println("Failed to reset login throttle:", err)
+	}
+	h.recordLoginAttempt(r, user.Username, user.ID.String(), models.LoginSucceeded)
+
+	// Each login is a separate session, so other devices stay signed in
+	current, refreshToken, err := h.Sessions.Create(r.Context(), user.ID.String(), r.UserAgent(), utils.ClientIP(r), mfa)
 	if err != nil {
 		log.Println("Failed to store session in Redis:", err)
 		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to store session", nil, err.Error())
 		return
 	}
 
-	utils.SendResponse(w, http.StatusOK, true, "Login successful", map[string]string{"token": token}, "")
+	h.sendTokens(w, "Login successful", user, current, refreshToken)
+}
+
+// sendLockedOut tells the client to wait before trying to log in again
+func sendLockedOut(w http.ResponseWriter, lockedFor time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(lockedFor.Round(time.Second).Seconds())))
+	utils.SendResponse(w, http.StatusTooManyRequests, false, "Too many failed login attempts; try again later", nil, "")
 }
 
-// Logout handles user logout
+// recordLoginAttempt writes the audit record of a login attempt
+func (h *AuthHandler) recordLoginAttempt(r *http.Request, username, userID, outcome string) {
+	attempt := models.LoginAttempt{
+		Username:  username,
+		UserID:    userID,
+		IP:        utils.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		Outcome:   outcome,
+	}
+	if err := h.DB.Create(&attempt).Error; err != nil {
+		log.Println("Failed to record login attempt:", err)
+	}
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token. The old refresh token stops working; presenting it again
+// revokes the session.
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, "refresh_token is required")
+		return
+	}
+
+	current, refreshToken, err := h.Sessions.Refresh(r.Context(), req.RefreshToken, utils.ClientIP(r))
+	switch {
+	case err == nil:
+	case errors.Is(err, session.ErrRefreshTokenReused):
+		log.Println("Refresh token reuse detected; session revoked")
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Session revoked; log in again", nil, err.Error())
+		return
+	case errors.Is(err, session.ErrInvalidRefreshToken):
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid refresh token", nil, "")
+		return
+	default:
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to refresh session", nil, err.Error())
+		return
+	}
+
+	// Reload the user so role changes apply from the next access token
+	var user models.User
+	if err := h.DB.Where("id = ?", current.UserID).First(&user).Error; err != nil {
+		h.Sessions.Revoke(r.Context(), current.UserID, current.ID)
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid refresh token", nil, "")
+		return
+	}
+
+	h.sendTokens(w, "Token refreshed", &user, current, refreshToken)
+}
+
+// sendTokens responds with a new access token for the session and its refresh token
+func (h *AuthHandler) sendTokens(w http.ResponseWriter, message string, user *models.User, current *session.Session, refreshToken string) {
+	token, err := utils.GenerateJWT(user.ID.String(), user.Role, current.ID, current.MFA)
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to generate token", nil, err.Error())
+		return
+	}
+
+	utils.SendResponse(w, http.StatusOK, true, message, map[string]interface{}{
+		"token":         token,
+		"expires_in":    int(utils.AccessTokenTTL().Seconds()),
+		"refresh_token": refreshToken,
+		"session_id":    current.ID,
+	}, "")
+}
+
+// Logout ends the current session and revokes its access token. With
+// ?all=true it signs the user out of every device.
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
 	token := r.Header.Get("Authorization")
 
 	if token == "" {
@@ -105,16 +269,25 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extract UserID from token
-	userID, err := utils.ParseJWT(token)
+	// Extract UserID and session from token
+	claims, err := utils.ParseAccessToken(strings.TrimPrefix(token, "Bearer "))
 	if err != nil {
 		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid token", nil, err.Error())
 		return
 	}
 
-	// Remove session from Redis using UserID
-	err = h.Redis.Del(ctx, userID).Err()
-	if err != nil {
+	if err := h.Denylist.Revoke(r.Context(), claims.ID, claims.ExpiresAt); err != nil {
+		log.Println("Failed to revoke token:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to logout", nil, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("all") == "true" {
+		_, err = h.Sessions.RevokeAll(r.Context(), claims.UserID)
+	} else {
+		err = h.Sessions.Revoke(r.Context(), claims.UserID, claims.SessionID)
+	}
+	if err != nil && !errors.Is(err, session.ErrNotFound) {
 		log.Println("Failed to delete session from Redis:", err)
 		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to logout", nil, err.Error())
 		return
@@ -122,3 +295,105 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 
 	utils.SendResponse(w, http.StatusOK, true, "Logged out successfully", nil, "")
 }
+
+// ChangePassword sets a new password and signs the user out of every
+// device, including this one.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, err.Error())
+		return
+	}
+	if len(req.NewPassword) < models.MinPasswordLength {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, fmt.Sprintf("new_password must be at least %d characters", models.MinPasswordLength))
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("id = ?", middleware.UserIDFromContext(r)).First(&user).Error; err != nil {
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid credentials", nil, "")
+		return
+	}
+	if !user.CheckPassword(req.CurrentPassword) {
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid credentials", nil, "")
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to hash password")
+		return
+	}
+	if err := h.DB.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to change password", nil, err.Error())
+		return
+	}
+
+	// Whoever knew the old password may hold tokens; end every session
+	claims := middleware.AccessClaimsFromContext(r)
+	if err := h.Denylist.Revoke(r.Context(), claims.ID, claims.ExpiresAt); err != nil {
+		log.Println("Failed to revoke token:", err)
+	}
+	if _, err := h.Sessions.RevokeAll(r.Context(), user.ID.String()); err != nil {
+		log.Println("Failed to revoke sessions after password change:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Password changed but sessions could not be revoked", nil, err.Error())
+		return
+	}
+
+	utils.SendResponse(w, http.StatusOK, true, "Password changed; log in again", nil, "")
+}
+
+// ListSessions lists the caller's signed-in devices
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r)
+	sessions, err := h.Sessions.List(r.Context(), userID)
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to list sessions", nil, err.Error())
+		return
+	}
+
+	type sessionView struct {
+		session.Session
+		Current bool `json:"current"`
+	}
+	currentID := middleware.SessionIDFromContext(r)
+	views := make([]sessionView, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, sessionView{Session: s, Current: s.ID == currentID})
+	}
+
+	utils.SendResponse(w, http.StatusOK, true, "Sessions retrieved successfully", views, "")
+}
+
+// RevokeSession signs out one of the caller's devices
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r)
+	err := h.Sessions.Revoke(r.Context(), userID, mux.Vars(r)["id"])
+	if errors.Is(err, session.ErrNotFound) {
+		utils.SendResponse(w, http.StatusNotFound, false, "Session not found", nil, "")
+		return
+	}
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to revoke session", nil, err.Error())
+		return
+	}
+
+	utils.SendResponse(w, http.StatusOK, true, "Session revoked successfully", nil, "")
+}
+
+// JWKS publishes the public keys access tokens are signed with, so other
+// services can verify them without sharing a secret
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	keys, err := utils.Keys()
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Signing keys are not configured")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(keys.JWKS())
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/api/middleware"
+	"github.com/ashil-poojary/banking-ledger-service/auth"
+	"github.com/ashil-poojary/banking-ledger-service/config"
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+)
+
+// LoginMFA completes a login that /api/login answered with an MFA challenge.
+// It takes the challenge token and a TOTP code or a recovery code.
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"` // TOTP or recovery code
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, "challenge_token and code are required")
+		return
+	}
+
+	userID, err := h.Challenges.User(r.Context(), req.ChallengeToken)
+	if errors.Is(err, auth.ErrInvalidChallenge) {
+		utils.SendResponse(w, http.StatusUnauthorized, false, "MFA challenge expired; log in again", nil, "")
+		return
+	}
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Login is temporarily unavailable", nil, "")
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		utils.SendResponse(w, http.StatusUnauthorized, false, "MFA challenge expired; log in again", nil, "")
+		return
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	ip := utils.ClientIP(r)
+	_, lockedFor, err := h.Throttle.Check(r.Context(), user.Username, ip)
+	if err != nil {
+		log.Println("Failed to check login throttle:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Login is temporarily unavailable", nil, "")
+		return
+	}
+	if lockedFor > 0 {
+		h.Challenges.Complete(r.Context(), req.ChallengeToken)
+		h.recordLoginAttempt(r, user.Username, userID, models.LoginLockedOut)
+		sendLockedOut(w, lockedFor)
+		return
+	}
+
+	err = auth.VerifyCode(h.DB, userID, req.Code, time.Now())
+	if errors.Is(err, auth.ErrInvalidMFACode) {
+		h.recordLoginAttempt(r, user.Username, userID, models.LoginInvalidMFACode)
+		if err := h.Challenges.Fail(r.Context(), req.ChallengeToken); err != nil {
+			log.Println("Failed to record failed MFA attempt:", err)
+		}
+		if locked, err := h.Throttle.Fail(r.Context(), user.Username, ip); err != nil {
+			log.Println("Failed to record failed login:", err)
+		} else if locked {
+			h.Challenges.Complete(r.Context(), req.ChallengeToken)
+			sendLockedOut(w, h.Throttle.Lockout)
+			return
+		}
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid MFA code", nil, "")
+		return
+	}
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to verify MFA code", nil, err.Error())
+		return
+	}
+
+	if err := h.Challenges.Complete(r.Context(), req.ChallengeToken); err != nil {
+		log.Println("Failed to complete MFA challenge:", err)
+	}
+	h.startSession(w, r, &user, true)
+}
+
+// EnrollMFA starts TOTP enrollment. The response holds the secret and an
+// otpauth:// URI to show as a QR code; MFA is on once ConfirmMFA succeeds.
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	var user models.User
+	if err := h.DB.Where("id = ?", middleware.UserIDFromContext(r)).First(&user).Error; err != nil {
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Unauthorized", nil, "")
+		return
+	}
+
+	factor, err := auth.StartEnrollment(h.DB, user.ID.String())
+	if errors.Is(err, auth.ErrMFAAlreadyEnabled) {
+		utils.SendResponse(w, http.StatusConflict, false, "MFA is already enabled; disable it first to enroll a new device", nil, "")
+		return
+	}
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to start MFA enrollment", nil, err.Error())
+		return
+	}
+
+	issuer := config.GetEnv("MFA_ISSUER", "Banking Ledger")
+	utils.SendResponse(w, http.StatusOK, true, "Scan the QR code, then confirm with a code", map[string]string{
+		"secret":           factor.Secret,
+		"provisioning_uri": auth.ProvisioningURI(issuer, user.Username, factor.Secret),
+	}, "")
+}
+
+// ConfirmMFA turns MFA on with a first code from the authenticator and
+// returns the recovery codes. They are shown only this once.
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, "code is required")
+		return
+	}
+
+	codes, err := auth.ConfirmEnrollment(h.DB, middleware.UserIDFromContext(r), req.Code, time.Now())
+	switch {
+	case err == nil:
+	case errors.Is(err, auth.ErrMFANotEnabled):
+		utils.SendResponse(w, http.StatusBadRequest, false, "Start enrollment first", nil, "")
+		return
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled):
+		utils.SendResponse(w, http.StatusConflict, false, "MFA is already enabled", nil, "")
+		return
+	case errors.Is(err, auth.ErrInvalidMFACode):
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid MFA code", nil, "")
+		return
+	default:
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to enable MFA", nil, err.Error())
+		return
+	}
+
+	utils.SendResponse(w, http.StatusOK, true, "MFA enabled; store the recovery codes safely", map[string][]string{"recovery_codes": codes}, "")
+}
+
+// DisableMFA turns MFA off. It needs the password and a current TOTP or
+// recovery code, so a stolen session alone cannot remove the factor. Every
+// session is ended, since they were signed in with the factor.
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, "password and code are required")
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("id = ?", middleware.UserIDFromContext(r)).First(&user).Error; err != nil || !user.CheckPassword(req.Password) {
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid credentials", nil, "")
+		return
+	}
+	if !h.verifyMFA(w, r, &user, req.Code) {
+		return
+	}
+
+	if err := auth.Disable(h.DB, user.ID.String()); err != nil && !errors.Is(err, auth.ErrMFANotEnabled) {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to disable MFA", nil, err.Error())
+		return
+	}
+
+	log.Printf("User %s disabled MFA", user.ID)
+
+	// Sessions signed in with the factor would still pass RequireMFA; end them all
+	claims := middleware.AccessClaimsFromContext(r)
+	if err := h.Denylist.Revoke(r.Context(), claims.ID, claims.ExpiresAt); err != nil {
+		log.Println("Failed to revoke token:", err)
+	}
+	if _, err := h.Sessions.RevokeAll(r.Context(), user.ID.String()); err != nil {
+		log.Println("Failed to revoke sessions after disabling MFA:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "MFA disabled but sessions could not be revoked", nil, err.Error())
+		return
+	}
+	utils.SendResponse(w, http.StatusOK, true, "MFA disabled; log in again", nil, "")
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes, e.g. when they are
+// running out. It needs a current TOTP or recovery code.
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		utils.SendResponse(w, http.StatusBadRequest, false, "Invalid request", nil, "code is required")
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("id = ?", middleware.UserIDFromContext(r)).First(&user).Error; err != nil {
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Unauthorized", nil, "")
+		return
+	}
+	if !h.verifyMFA(w, r, &user, req.Code) {
+		return
+	}
+
+	codes, err := auth.RegenerateRecoveryCodes(h.DB, user.ID.String())
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to regenerate recovery codes", nil, err.Error())
+		return
+	}
+
+	utils.SendResponse(w, http.StatusOK, true, "Recovery codes regenerated; the old ones no longer work", map[string][]string{"recovery_codes": codes}, "")
+}
+
+// verifyMFA checks a code for an MFA-protected change and responds if it
+// does not pass. Wrong codes count towards the login lockout, so a stolen
+// session cannot be used to guess codes.
+func (h *AuthHandler) verifyMFA(w http.ResponseWriter, r *http.Request, user *models.User, code string) bool {
+	ip := utils.ClientIP(r)
+	_, lockedFor, err := h.Throttle.Check(r.Context(), user.Username, ip)
+	if err != nil {
+		log.Println("Failed to check login throttle:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to verify MFA code", nil, "")
+		return false
+	}
+	if lockedFor > 0 {
+		sendLockedOut(w, lockedFor)
+		return false
+	}
+
+	err = auth.VerifyCode(h.DB, user.ID.String(), code, time.Now())
+	switch {
+	case err == nil:
+		if err := h.Throttle.Succeed(r.Context(), user.Username); err != nil {
+			log.Println("Failed to reset login throttle:", err)
+		}
+		return true
+	case errors.Is(err, auth.ErrMFANotEnabled):
+		utils.SendResponse(w, http.StatusBadRequest, false, "MFA is not enabled", nil, "")
+	case errors.Is(err, auth.ErrInvalidMFACode):
+		locked, err := h.Throttle.Fail(r.Context(), user.Username, ip)
+		if err != nil {
+			log.Println("Failed to record failed MFA attempt:", err)
+		}
+		if locked {
+			sendLockedOut(w, h.Throttle.Lockout)
+			return false
+		}
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Invalid MFA code", nil, "")
+	default:
+		utils.SendResponse(w, http.StatusInternalServerError, false, "Failed to verify MFA code", nil, err.Error())
+	}
+	return false
+}
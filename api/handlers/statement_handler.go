@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/statement"
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// maxStatementPeriod bounds the period covered by a single statement
+const maxStatementPeriod = 366 * 24 * time.Hour
+
+// statementFormats maps each supported format to its content type and file extension
+var statementFormats = map[string]struct {
+	contentType string
+	extension   string
+	write       func(io.Writer, *statement.Statement) error
+}{
+	"csv":     {"text/csv; charset=utf-8", "csv", statement.WriteCSV},
+	"pdf":     {"application/pdf", "pdf", statement.WritePDF},
+	"camt053": {"application/xml; charset=utf-8", "xml", statement.WriteCamt053},
+}
+
+// GetStatement produces the statement of an account for postings made from
+// `from` (inclusive) to `to` (exclusive), by default the current month so
+// far. `format` is csv (default), pdf or camt053.
+func (h *AccountHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	accountNumber := mux.Vars(r)["number"]
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	renderer, ok := statementFormats[format]
+	if !ok {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Format must be one of csv, pdf or camt053")
+		return
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now
+	if t, err := models.ParseTime(query.Get("from")); err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Invalid from: "+err.Error())
+		return
+	} else if t != nil {
+		from = *t
+	}
+	if t, err := models.ParseTime(query.Get("to")); err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Invalid to: "+err.Error())
+		return
+	} else if t != nil {
+		to = *t
+	}
+	if !from.Before(to) {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "from must be before to")
+		return
+	}
+	if to.Sub(from) > maxStatementPeriod {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "A statement can cover at most one year")
+		return
+	}
+
+	s, err := statement.Build(h.DB, accountNumber, from, to)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.SendResponse(w, http.StatusNotFound, false, "", nil, "Account not found")
+		return
+	}
+	if err != nil {
+		log.Println("Failed to build statement:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to build statement")
+		return
+	}
+
+	// Render fully before writing so a failure can still be reported as JSON
+	var buf bytes.Buffer
+	if err := renderer.write(&buf, s); err != nil {
+		log.Println("Failed to render statement:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to render statement")
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s-%s.%s", accountNumber, from.Format("20060102"), to.Format("20060102"), renderer.extension)
+	w.Header().Set("Content-Type", renderer.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
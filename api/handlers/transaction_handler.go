@@ -3,13 +3,19 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/ashil-poojary/banking-ledger-service/api/middleware"
+	"github.com/ashil-poojary/banking-ledger-service/ledger"
 	"github.com/ashil-poojary/banking-ledger-service/models"
 	"github.com/ashil-poojary/banking-ledger-service/utils"
 	"github.com/ashil-poojary/banking-ledger-service/worker"
+	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"gorm.io/gorm"
 )
@@ -20,11 +26,12 @@ type TransactionHandler struct {
 	MongoDB    *mongo.Database
 	RabbitMQ   worker.RabbitMQPublisher // ✅ Use the interface
 	QueueName  string
+	FXRates    ledger.FXRateProvider
 }
 
 // NewTransactionHandler initializes a new TransactionHandler
-func NewTransactionHandler(postgresDB *gorm.DB, mongoDB *mongo.Database, rabbitMQ worker.RabbitMQPublisher, queueName string) *TransactionHandler {
-	return &TransactionHandler{PostgresDB: postgresDB, MongoDB: mongoDB, RabbitMQ: rabbitMQ, QueueName: queueName}
+func NewTransactionHandler(postgresDB *gorm.DB, mongoDB *mongo.Database, rabbitMQ worker.RabbitMQPublisher, queueName string, fxRates ledger.FXRateProvider) *TransactionHandler {
+	return &TransactionHandler{PostgresDB: postgresDB, MongoDB: mongoDB, RabbitMQ: rabbitMQ, QueueName: queueName, FXRates: fxRates}
 }
 
 // TransferFunds handles money transfers between accounts
@@ -36,125 +43,263 @@ func (h *TransactionHandler) TransferFunds(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Validate request data
-	if transferReq.SourceAccount == "" || transferReq.DestinationAccount == "" || transferReq.Amount <= 0 {
+	if transferReq.SourceAccount == "" || transferReq.DestinationAccount == "" ||
+		transferReq.SourceAccount == transferReq.DestinationAccount || !transferReq.Amount.IsPositive() {
 		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Invalid transfer details")
 		return
 	}
 
-	// Start a transaction in PostgreSQL
-	tx := h.PostgresDB.Begin()
+	// Lock both accounts, post the transfer and queue its event in one
+	// transaction; it is re-run if Postgres aborts it to break a conflict
+	idempotencyKey := middleware.IdempotencyKeyFromContext(r)
+	var txn models.Transaction
+	err := ledger.RunInTransaction(h.PostgresDB, func(tx *gorm.DB) error {
+		entry, conversion, err := ledger.Transfer(tx, h.FXRates, transferReq.SourceAccount, transferReq.DestinationAccount, transferReq.Amount, idempotencyKey)
+		if err != nil {
+			return err
+		}
 
-	// Fetch source account
-	var sourceAccount models.Account
-	if err := tx.Where("account_number = ?", transferReq.SourceAccount).First(&sourceAccount).Error; err != nil {
-		tx.Rollback()
+		// Create transaction log
+		now := time.Now()
+		txn = models.Transaction{
+			ID:                 primitive.NewObjectID(),
+			SourceAccount:      transferReq.SourceAccount,
+			DestinationAccount: transferReq.DestinationAccount,
+			Amount:             transferReq.Amount,
+			Type:               "transfer",
+			FX:                 conversion,
+			Reference:          entry.ID,
+			IdempotencyKey:     idempotencyKey,
+			CreatedAt:          now,
+		}
+
+		// The transfer was posted synchronously, so it runs through its whole lifecycle here
+		for _, status := range []string{models.StatusPending, models.StatusProcessing, models.StatusCompleted} {
+			txn.TransitionTo(status, "", now)
+		}
+
+		// Queue the event in the outbox; the relay publishes it to RabbitMQ and
+		// the worker writes the MongoDB log once this transaction commits
+		return worker.EnqueueTransaction(tx, txn, h.QueueName)
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, ledger.ErrSourceNotFound):
 		utils.SendResponse(w, http.StatusNotFound, false, "", nil, "Source account not found")
 		return
-	}
-
-	// Check if the source account has enough balance
-	if sourceAccount.Balance < transferReq.Amount {
-		tx.Rollback()
+	case errors.Is(err, ledger.ErrDestinationNotFound), errors.Is(err, ledger.ErrDestinationClosed):
+		utils.SendResponse(w, http.StatusNotFound, false, "", nil, "Destination account not found")
+		return
+	case errors.Is(err, ledger.ErrCurrencyMismatch):
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Transfer currency must match the source or destination account currency")
+		return
+	case errors.Is(err, ledger.ErrConversion):
+		log.Println("Failed to convert transfer:", err)
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Currency conversion is not available for this transfer")
+		return
+	case errors.Is(err, ledger.ErrAccountFrozen):
+		utils.SendResponse(w, http.StatusForbidden, false, "", nil, "Source account is frozen")
+		return
+	case errors.Is(err, ledger.ErrAccountDormant):
+		utils.SendResponse(w, http.StatusForbidden, false, "", nil, "Source account is dormant")
+		return
+	case errors.Is(err, ledger.ErrInsufficientFunds):
 		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Insufficient funds")
 		return
-	}
-
-	// Fetch destination account
-	var destinationAccount models.Account
-	if err := tx.Where("account_number = ?", transferReq.DestinationAccount).First(&destinationAccount).Error; err != nil {
-		tx.Rollback()
-		utils.SendResponse(w, http.StatusNotFound, false, "", nil, "Destination account not found")
+	default:
+		log.Println("Failed to process transfer:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to process transfer")
 		return
 	}
 
-	// Deduct from source and add to destination
-	sourceAccount.Balance -= transferReq.Amount
-	destinationAccount.Balance += transferReq.Amount
+	utils.SendResponse(w, http.StatusOK, true, "Transfer successful", txn, "")
+}
 
-	if err := tx.Save(&sourceAccount).Error; err != nil || tx.Save(&destinationAccount).Error != nil {
-		tx.Rollback()
-		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to process transfer")
+// Deposit queues a deposit into one of the caller's accounts
+func (h *TransactionHandler) Deposit(w http.ResponseWriter, r *http.Request) {
+	h.queueAccountTransaction(w, r, "deposit")
+}
+
+// Withdraw queues a withdrawal from one of the caller's accounts
+func (h *TransactionHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	h.queueAccountTransaction(w, r, "withdrawal")
+}
+
+// queueAccountTransaction records a pending deposit or withdrawal and hands it
+// to the worker. The response is 202 with an ID whose status can be polled.
+func (h *TransactionHandler) queueAccountTransaction(w http.ResponseWriter, r *http.Request, txType string) {
+	userID, err := utils.ExtractUserID(r)
+	if err != nil {
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Unauthorized", nil, err.Error())
 		return
 	}
 
-	// Commit transaction
-	tx.Commit()
+	var req models.AccountTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Invalid request payload")
+		return
+	}
 
-	// Create transaction log
+	now := time.Now()
 	txn := models.Transaction{
-		SourceAccount:      transferReq.SourceAccount,
-		DestinationAccount: transferReq.DestinationAccount,
-		AccountNumber:      transferReq.SourceAccount,
-		Amount:             transferReq.Amount,
-		Currency:           transferReq.Currency,
-		Type:               "transfer",
-		Status:             "completed",
+		ID:             primitive.NewObjectID(),
+		AccountNumber:  req.AccountNumber,
+		Amount:         req.Amount,
+		Type:           txType,
+		IdempotencyKey: middleware.IdempotencyKeyFromContext(r),
+		CreatedAt:      now,
+	}
+	txn.TransitionTo(models.StatusPending, "", now)
+	if err := txn.Validate(); err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
 	}
 
-	// Store in MongoDB
-	_, err := h.MongoDB.Collection("transactions").InsertOne(context.TODO(), txn)
-	if err != nil {
-		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to log transaction")
+	// Ensure the account belongs to the user
+	var account models.Account
+	if err := h.PostgresDB.Where("account_number = ? AND user_id = ?", req.AccountNumber, userID).First(&account).Error; err != nil {
+		utils.SendResponse(w, http.StatusNotFound, false, "", nil, "Account not found")
+		return
+	}
+	if account.Currency != req.Amount.Currency {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Currency must match the account currency")
+		return
+	}
+	if txType == "withdrawal" && !account.AllowsDebits() {
+		utils.SendResponse(w, http.StatusForbidden, false, "", nil, "Account is "+account.Status)
+		return
+	}
+
+	// Record the pending transaction so its status can be polled
+	if _, err := h.MongoDB.Collection("transactions").InsertOne(context.TODO(), txn); err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to record transaction")
 		return
 	}
 
-	// Publish event to RabbitMQ
 	if err := worker.PublishTransaction(txn, h.RabbitMQ, h.QueueName); err != nil {
+		if _, err := worker.TransitionStatus(h.MongoDB, txn.ID, models.StatusFailed, "could not be queued for processing", nil); err != nil {
+			log.Println("Failed to mark transaction as failed:", err)
+		}
 		utils.SendResponse(w, http.StatusServiceUnavailable, false, "", nil, "RabbitMQ is unavailable. Please try again later.")
 		return
 	}
 
-	utils.SendResponse(w, http.StatusOK, true, "Transfer successful", txn, "")
+	utils.SendResponse(w, http.StatusAccepted, true, "Transaction accepted for processing", map[string]string{
+		"transaction_id": txn.ID.Hex(),
+		"status":         txn.Status,
+	}, "")
 }
 
-// GetTransaction retrieves a specific transaction from PostgreSQL
-func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
-	accountNumber := r.URL.Query().Get("account_number") // 🔹 Ensure correct query param name
+// GetTransactionByID returns the current state of a transaction and the
+// history of its status changes
+func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ExtractUserID(r)
+	if err != nil {
+		utils.SendResponse(w, http.StatusUnauthorized, false, "Unauthorized", nil, err.Error())
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Invalid transaction ID")
+		return
+	}
 
-	if accountNumber == "" {
-		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Account number is required")
+	var txn models.Transaction
+	if err := h.MongoDB.Collection("transactions").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&txn); err != nil {
+		utils.SendResponse(w, http.StatusNotFound, false, "", nil, "Transaction not found")
 		return
 	}
 
-	var transactions []models.Transaction
-	err := h.PostgresDB.Where("account_number = ?", accountNumber).Find(&transactions).Error
+	// Only the owner of an account involved in the transaction may see it
+	owned, err := middleware.OwnsAnyAccount(h.PostgresDB, userID, txn.AccountNumber, txn.SourceAccount, txn.DestinationAccount)
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to retrieve transaction")
+		return
+	}
+	if !owned {
+		utils.SendResponse(w, http.StatusNotFound, false, "", nil, "Transaction not found")
+		return
+	}
+
+	utils.SendResponse(w, http.StatusOK, true, "Transaction retrieved successfully", txn, "")
+}
+
+// GetTransaction lists posted transactions from the PostgreSQL ledger for one
+// of the caller's accounts, or for all of them if no account is given. It
+// accepts the same filters and pagination as GetTransactionHistory.
+func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	query, err := models.ParseTransactionQuery(r.URL.Query())
+	if err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+	scoped, err := h.scopeToAccounts(r, &query)
 	if err != nil {
 		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to retrieve transactions")
 		return
 	}
+	if !scoped {
+		sendEmptyPage(w, "Transactions retrieved successfully")
+		return
+	}
 
-	if len(transactions) == 0 {
-		utils.SendResponse(w, http.StatusNotFound, false, "", nil, "No transactions found for this account")
+	transactions, nextCursor, err := ledger.ListTransactions(h.PostgresDB, query)
+	if err != nil {
+		log.Println("Failed to list transactions:", err)
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to retrieve transactions")
 		return
 	}
 
-	utils.SendResponse(w, http.StatusOK, true, "Transactions retrieved successfully", transactions, "")
+	utils.SendResponse(w, http.StatusOK, true, "Transactions retrieved successfully", map[string]interface{}{
+		"transactions": transactions,
+		"next_cursor":  nextCursor,
+	}, "")
 }
 
-// GetTransactionHistory retrieves transaction logs from MongoDB
+// GetTransactionHistory retrieves transaction logs from MongoDB for one of
+// the caller's accounts, or for all of them if no account is given. Results
+// are filtered and paged as described by models.ParseTransactionQuery.
 func (h *TransactionHandler) GetTransactionHistory(w http.ResponseWriter, r *http.Request) {
-	accountNumber := r.URL.Query().Get("account_number") // 🔹 Ensure correct query param name
+	query, err := models.ParseTransactionQuery(r.URL.Query())
+	if err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
+	}
+	scoped, err := h.scopeToAccounts(r, &query)
+	if err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to retrieve transaction history")
+		return
+	}
+	if !scoped {
+		sendEmptyPage(w, "Transaction history retrieved successfully")
+		return
+	}
 
-	var filter bson.M
-	if accountNumber != "" {
-		filter = bson.M{"account_number": accountNumber} // 🔹 Filter by account if provided
-	} else {
-		filter = bson.M{} // 🔹 Retrieve all transactions if no filter is given
+	// 🔹 Filter by account (either side of a transfer); ownership is checked by the route
+	filter, err := mongoTransactionFilter(query)
+	if err != nil {
+		utils.SendResponse(w, http.StatusBadRequest, false, "", nil, err.Error())
+		return
 	}
 
-	cursor, err := h.MongoDB.Collection("transactions").Find(context.TODO(), filter)
+	cursor, err := h.MongoDB.Collection("transactions").Find(context.TODO(), filter, mongoTransactionOptions(query))
 	if err != nil {
 		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to retrieve transaction history")
 		return
 	}
 	defer cursor.Close(context.TODO())
 
-	var transactions []models.Transaction
+	transactions := []models.Transaction{}
 	if err = cursor.All(context.TODO(), &transactions); err != nil {
 		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Error decoding transaction history")
 		return
 	}
 
-	log.Println("[DEBUG] Transaction history:", transactions)
-	utils.SendResponse(w, http.StatusOK, true, "Transaction history retrieved successfully", transactions, "")
+	transactions, nextCursor := pageTransactions(transactions, query.Limit)
+	utils.SendResponse(w, http.StatusOK, true, "Transaction history retrieved successfully", map[string]interface{}{
+		"transactions": transactions,
+		"next_cursor":  nextCursor,
+	}, "")
 }
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTransactionDB opens an in-memory database where alice owns two
+// accounts with a transfer and a deposit between them
+func setupTransactionDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Account{}, &models.LedgerTransaction{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	for _, number := range []string{"1111111111", "2222222222"} {
+		account := models.Account{UserID: "alice", AccountNumber: number, OwnerName: "alice", AccountType: "Savings", Currency: "USD"}
+		if err := db.Create(&account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+
+	// A transfer leaves account_number blank and a deposit leaves both sides blank
+	transactions := []models.LedgerTransaction{
+		{JournalEntryID: "entry-1", Type: "transfer", SourceAccount: "1111111111", DestinationAccount: "2222222222", Amount: models.NewMoney(1000, "USD"), Currency: "USD"},
+		{JournalEntryID: "entry-2", Type: "deposit", AccountNumber: "1111111111", Amount: models.NewMoney(5000, "USD"), Currency: "USD"},
+	}
+	for i := range transactions {
+		if err := db.Create(&transactions[i]).Error; err != nil {
+			t.Fatalf("Failed to create transaction: %v", err)
+		}
+	}
+	return db
+}
+
+// TestGetTransactionScope tests that callers only see transactions of their
+// own accounts
+func TestGetTransactionScope(t *testing.T) {
+	handler := NewTransactionHandler(setupTransactionDB(t), nil, nil, "", nil)
+
+	tests := []struct {
+		name          string
+		userID        string
+		url           string
+		expectedCount int
+	}{
+		// ✅ Every account of the caller
+		{"All own accounts", "alice", "/api/transaction", 2},
+		// ✅ One named account
+		{"One account", "alice", "/api/transaction?account_number=2222222222", 1},
+		// ❌ A caller without accounts sees nothing, not everyone's transactions
+		{"No accounts", "carol", "/api/transaction", 0},
+		{"No accounts with filter", "carol", "/api/transaction?type=deposit", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			req := httptest.NewRequest("GET", tc.url, nil)
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", tc.userID))
+			rec := httptest.NewRecorder()
+			handler.GetTransaction(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var response struct {
+				Data struct {
+					Transactions []models.LedgerTransaction `json:"transactions"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Data.Transactions) != tc.expectedCount {
+				t.Errorf("Expected %d transactions, got %d", tc.expectedCount, len(response.Data.Transactions))
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ashil-poojary/banking-ledger-service/api/middleware"
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scopeToAccounts limits a query to the requested account, or to every
+// account of the caller if none is given. Ownership of a requested account
+// is checked by the route. It returns false if the caller owns no accounts,
+// in which case there is nothing to list.
+func (h *TransactionHandler) scopeToAccounts(r *http.Request, query *models.TransactionQuery) (bool, error) {
+	if accountNumber := r.URL.Query().Get("account_number"); accountNumber != "" {
+		query.AccountNumbers = []string{accountNumber}
+		return true, nil
+	}
+
+	accountNumbers, err := middleware.OwnedAccountNumbers(h.PostgresDB, middleware.UserIDFromContext(r))
+	if err != nil {
+		return false, err
+	}
+	query.AccountNumbers = query.AccountNumbers[:0]
+	for _, number := range accountNumbers {
+		if number != "" {
+			query.AccountNumbers = append(query.AccountNumbers, number)
+		}
+	}
+	return len(query.AccountNumbers) > 0, nil
+}
+
+// errNoAccounts is returned for a query limited to accounts that are all blank
+var errNoAccounts = errors.New("no account to list transactions for")
+
+// mongoTransactionFilter translates a transaction query into a Mongo filter,
+// including the keyset condition that starts the page after the cursor
+func mongoTransactionFilter(q models.TransactionQuery) (bson.M, error) {
+	conditions := []bson.M{}
+
+	if len(q.AccountNumbers) > 0 {
+		accounts := q.Accounts()
+		if len(accounts) == 0 {
+			return nil, errNoAccounts
+		}
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"account_number": bson.M{"$in": accounts}},
+			{"source_account": bson.M{"$in": accounts}},
+			{"destination_account": bson.M{"$in": accounts}},
+		}})
+	}
+	if q.Counterparty != "" {
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"source_account": q.Counterparty},
+			{"destination_account": q.Counterparty},
+		}})
+	}
+
+	createdAt := bson.M{}
+	if q.From != nil {
+		createdAt["$gte"] = *q.From
+	}
+	if q.To != nil {
+		createdAt["$lt"] = *q.To
+	}
+	if len(createdAt) > 0 {
+		conditions = append(conditions, bson.M{"created_at": createdAt})
+	}
+
+	if q.Type != "" {
+		conditions = append(conditions, bson.M{"type": q.Type})
+	}
+	if q.Status != "" {
+		conditions = append(conditions, bson.M{"status": q.Status})
+	}
+	if q.Currency != "" {
+		conditions = append(conditions, bson.M{"amount.currency": q.Currency})
+	}
+	amount := bson.M{}
+	if q.MinAmount != nil {
+		amount["$gte"] = q.MinAmount.MinorUnits
+	}
+	if q.MaxAmount != nil {
+		amount["$lte"] = q.MaxAmount.MinorUnits
+	}
+	if len(amount) > 0 {
+		conditions = append(conditions, bson.M{"amount.minor_units": amount})
+	}
+
+	if q.After != nil {
+		id, err := primitive.ObjectIDFromHex(q.After.ID)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		op := "$lt"
+		if q.Ascending {
+			op = "$gt"
+		}
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"created_at": bson.M{op: q.After.CreatedAt}},
+			{"created_at": q.After.CreatedAt, "_id": bson.M{op: id}},
+		}})
+	}
+
+	if len(conditions) == 0 {
+		return bson.M{}, nil
+	}
+	return bson.M{"$and": conditions}, nil
+}
+
+// mongoTransactionOptions sorts by the cursor key and fetches one extra
+// document to tell whether another page follows
+func mongoTransactionOptions(q models.TransactionQuery) *options.FindOptions {
+	direction := -1
+	if q.Ascending {
+		direction = 1
+	}
+	return options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(q.Limit + 1))
+}
+
+// pageTransactions trims the extra document fetched by
+// mongoTransactionOptions and returns the cursor for the next page, or "" on
+// the last page
+func pageTransactions(transactions []models.Transaction, limit int) ([]models.Transaction, string) {
+	if len(transactions) <= limit {
+		return transactions, ""
+	}
+	transactions = transactions[:limit]
+	last := transactions[limit-1]
+	return transactions, models.PageCursor{CreatedAt: last.CreatedAt, ID: last.ID.Hex()}.Encode()
+}
+
+// sendEmptyPage responds with a listing that has no transactions
+func sendEmptyPage(w http.ResponseWriter, message string) {
+	utils.SendResponse(w, http.StatusOK, true, message, map[string]interface{}{
+		"transactions": []interface{}{},
+		"next_cursor":  "",
+	}, "")
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestMongoTransactionFilterAccounts tests that the history filter never
+// matches on a blank account number
+func TestMongoTransactionFilterAccounts(t *testing.T) {
+	tests := []struct {
+		name             string
+		accounts         []string
+		expectedAccounts []string
+		expectedErr      error
+	}{
+		// ✅ Named accounts
+		{"One account", []string{"1111111111"}, []string{"1111111111"}, nil},
+		// ✅ Blanks are dropped
+		{"Blank among accounts", []string{"", "1111111111"}, []string{"1111111111"}, nil},
+		// ❌ Only blanks would match every transfer and deposit
+		{"Only blank", []string{""}, nil, errNoAccounts},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			filter, err := mongoTransactionFilter(models.TransactionQuery{AccountNumbers: tc.accounts})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			sides := filter["$and"].([]bson.M)[0]["$or"].([]bson.M)
+			for _, side := range sides {
+				for field, condition := range side {
+					in := condition.(bson.M)["$in"].([]string)
+					if fmt.Sprint(in) != fmt.Sprint(tc.expectedAccounts) {
+						t.Errorf("Expected %s in %v, got %v", field, tc.expectedAccounts, in)
+					}
+				}
+			}
+		})
+	}
+}
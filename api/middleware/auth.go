@@ -2,18 +2,21 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/session"
 	"github.com/ashil-poojary/banking-ledger-service/utils"
-	"github.com/go-redis/redis/v8"
 )
 
-// AuthMiddleware checks if a user is authenticated
-func AuthMiddleware(redisClient *redis.Client) func(http.Handler) http.Handler {
+// AuthMiddleware checks that the request carries a valid, unrevoked access
+// token for a live session
+func AuthMiddleware(sessions *session.Store, denylist *session.Denylist) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := context.Background()
 			authHeader := r.Header.Get("Authorization")
 
 			if authHeader == "" {
@@ -24,27 +27,78 @@ func AuthMiddleware(redisClient *redis.Client) func(http.Handler) http.Handler {
 			// Extract token from "Bearer <token>"
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-			// Validate JWT and extract user_id
-			userID, err := utils.ParseJWT(tokenString)
-			if err != nil {
+			// Validate JWT and extract user_id, role and session
+			claims, err := utils.ParseAccessToken(tokenString)
+			if err != nil || claims.ID == "" || claims.SessionID == "" {
 				utils.SendResponse(w, http.StatusUnauthorized, false, "", nil, "Invalid Authorization")
 				return
 			}
 
-			// Check if session exists in Redis using user_id
-			exists, err := redisClient.Exists(ctx, userID).Result()
+			revoked, err := denylist.IsRevoked(r.Context(), claims.ID)
 			if err != nil {
-				utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to check session")
+				utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to check token")
+				return
+			}
+			if revoked {
+				utils.SendResponse(w, http.StatusUnauthorized, false, "", nil, "Invalid Authorization")
 				return
 			}
-			if exists == 0 {
+
+			// The session must still exist and belong to the token's user
+			current, err := sessions.Get(r.Context(), claims.SessionID)
+			if errors.Is(err, session.ErrNotFound) || (err == nil && current.UserID != claims.UserID) {
 				utils.SendResponse(w, http.StatusUnauthorized, false, "", nil, "Invalid Authorization")
 				return
 			}
+			if err != nil {
+				utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to check session")
+				return
+			}
+			if err := sessions.Touch(r.Context(), current, utils.ClientIP(r)); err != nil {
+				log.Println("Failed to update session last seen time:", err)
+			}
 
-			// Store user_id in context for further request handling
-			ctx = context.WithValue(r.Context(), "user_id", userID)
+			// Store user_id, role, session_id and the token's claims in context for further request handling
+			role := claims.Role
+			if role == "" {
+				role = models.RoleCustomer
+			}
+			ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
+			ctx = context.WithValue(ctx, "role", role)
+			ctx = context.WithValue(ctx, "session_id", claims.SessionID)
+			ctx = context.WithValue(ctx, "access_claims", claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// SessionIDFromContext returns the session set by AuthMiddleware, or "" if
+// the request is not authenticated.
+func SessionIDFromContext(r *http.Request) string {
+	sessionID, _ := r.Context().Value("session_id").(string)
+	return sessionID
+}
+
+// AccessClaimsFromContext returns the claims of the access token checked by
+// AuthMiddleware, or nil if the request is not authenticated.
+func AccessClaimsFromContext(r *http.Request) *utils.AccessClaims {
+	claims, _ := r.Context().Value("access_claims").(*utils.AccessClaims)
+	return claims
+}
+
+// RequireMFA rejects requests whose session was not signed in with a second
+// factor. It must run after AuthMiddleware. When not required it does nothing.
+func RequireMFA(required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !required {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims := AccessClaimsFromContext(r); claims == nil || !claims.MFA {
+				utils.SendResponse(w, http.StatusForbidden, false, "", nil, "Multi-factor authentication is required; enable it and log in again")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
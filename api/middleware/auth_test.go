@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+)
+
+// TestRequireMFA tests that money-moving routes can be limited to MFA sessions
+func TestRequireMFA(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		required       bool
+		claims         *utils.AccessClaims
+		expectedStatus int
+	}{
+		// ✅ Signed in with a second factor
+		{"MFA session", true, &utils.AccessClaims{UserID: "alice", MFA: true}, http.StatusOK},
+		// ❌ Password only
+		{"Password session", true, &utils.AccessClaims{UserID: "alice"}, http.StatusForbidden},
+		// ❌ Not authenticated
+		{"No claims", true, nil, http.StatusForbidden},
+		// ✅ Not required
+		{"Not required", false, &utils.AccessClaims{UserID: "alice"}, http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			req := httptest.NewRequest("POST", "/api/ammount-transfer", nil)
+			if tc.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), "access_claims", tc.claims))
+			}
+			rec := httptest.NewRecorder()
+			RequireMFA(tc.required)(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
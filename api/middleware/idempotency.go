@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyHeader is the request header carrying the client's idempotency key
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyLease is how long a request holds its key without renewing it.
+// The lease is renewed while the handler runs, so only a request whose
+// server died loses it; a retry may then take the key over.
+var IdempotencyLease = time.Minute
+
+// Idempotency makes a money-moving handler safe to retry. The first request
+// with a given Idempotency-Key runs normally and its response is stored; a
+// repeat with the same body replays that response, and a repeat with a
+// different body is rejected with 422. Server errors are not stored, so the
+// request can be retried. It must run after AuthMiddleware.
+func Idempotency(db *gorm.DB) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, _ := r.Context().Value("user_id").(string)
+			if userID == "" {
+				utils.SendResponse(w, http.StatusUnauthorized, false, "", nil, "Invalid Authorization")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewBuffer(body)) // Restore body for the handler
+
+			leaseToken, leaseUntil := uuid.NewString(), time.Now().Add(IdempotencyLease)
+			record := models.IdempotencyKey{
+				UserID:      userID,
+				Key:         key,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				Fingerprint: fingerprint(r.Method, r.URL.Path, body),
+				LeaseToken:  leaseToken,
+				LeaseUntil:  &leaseUntil,
+			}
+
+			// Claim the key; if it already exists, replay or reject instead
+			claimed, err := claimKey(db, record)
+			if err != nil {
+				log.Println("Failed to store idempotency key:", err)
+				utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to process idempotency key")
+				return
+			}
+			if !claimed {
+				replayStoredResponse(w, db, record)
+				return
+			}
+
+			// Run the handler and store whatever it responded with
+			wr := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK, body: &bytes.Buffer{}}
+			ctx := context.WithValue(r.Context(), "idempotency_key", ScopedIdempotencyKey(userID, key))
+			stopRenewing := renewLease(db, userID, key, leaseToken)
+			next.ServeHTTP(wr, r.WithContext(ctx))
+			stopRenewing()
+
+			// Only the holder of the lease stores its result; if the key was
+			// taken over, the other request's result wins. A server error
+			// releases the key so the retry runs again.
+			owned := db.Where("user_id = ? AND key = ? AND lease_token = ? AND completed_at IS NULL", userID, key, leaseToken)
+			if wr.statusCode >= http.StatusInternalServerError {
+				if err := owned.Delete(&models.IdempotencyKey{}).Error; err != nil {
+					log.Println("Failed to release idempotency key:", err)
+				}
+				return
+			}
+
+			now := time.Now()
+			err = owned.Model(&models.IdempotencyKey{}).
+				Updates(map[string]interface{}{
+					"status_code":   wr.statusCode,
+					"response_body": wr.body.Bytes(),
+					"completed_at":  now,
+				}).Error
+			if err != nil {
+				log.Println("Failed to store idempotent response:", err)
+			}
+		})
+	}
+}
+
+// IdempotencyKeyFromContext returns the user-scoped idempotency key of the
+// current request, or "" if the client did not send one.
+func IdempotencyKeyFromContext(r *http.Request) string {
+	key, _ := r.Context().Value("idempotency_key").(string)
+	return key
+}
+
+// ScopedIdempotencyKey namespaces a client key by user so that two users
+// choosing the same key never collide downstream.
+func ScopedIdempotencyKey(userID, key string) string {
+	return userID + ":" + key
+}
+
+// claimKey records the key for a new request. A key left unfinished past its
+// lease by an earlier attempt with the same request is taken over. It
+// returns false if the key is held or already has a response.
+func claimKey(db *gorm.DB, record models.IdempotencyKey) (bool, error) {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	result = db.Model(&models.IdempotencyKey{}).
+		Where("user_id = ? AND key = ? AND fingerprint = ? AND completed_at IS NULL", record.UserID, record.Key, record.Fingerprint).
+		Where("lease_until IS NULL OR lease_until < ?", time.Now()).
+		Updates(map[string]interface{}{"lease_token": record.LeaseToken, "lease_until": record.LeaseUntil})
+	return result.RowsAffected == 1, result.Error
+}
+
+// renewLease extends the lease on a key every third of IdempotencyLease
+// until the returned function is called, so a slow request keeps its key.
+func renewLease(db *gorm.DB, userID, key, leaseToken string) (stop func()) {
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(IdempotencyLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := db.Model(&models.IdempotencyKey{}).
+					Where("user_id = ? AND key = ? AND lease_token = ? AND completed_at IS NULL", userID, key, leaseToken).
+					Update("lease_until", time.Now().Add(IdempotencyLease)).Error
+				if err != nil {
+					log.Println("Failed to renew idempotency key:", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// replayStoredResponse answers a repeated request from the stored record.
+func replayStoredResponse(w http.ResponseWriter, db *gorm.DB, record models.IdempotencyKey) {
+	var existing models.IdempotencyKey
+	if err := db.Where("user_id = ? AND key = ?", record.UserID, record.Key).First(&existing).Error; err != nil {
+		utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to process idempotency key")
+		return
+	}
+
+	if existing.Fingerprint != record.Fingerprint {
+		utils.SendResponse(w, http.StatusUnprocessableEntity, false, "", nil, "Idempotency key was already used with a different request")
+		return
+	}
+
+	if !existing.Completed() {
+		utils.SendResponse(w, http.StatusConflict, false, "", nil, "A request with this idempotency key is still in progress")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(existing.StatusCode)
+	w.Write(existing.ResponseBody)
+}
+
+// fingerprint identifies a request by method, path and body.
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
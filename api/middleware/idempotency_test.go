@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestIdempotency tests replay and rejection of repeated Idempotency-Key requests
+func TestIdempotency(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	calls := 0
+	handler := Idempotency(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"call":%d,"key":%q}`, calls, IdempotencyKeyFromContext(r))
+	}))
+
+	send := func(userID, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/ammount-transfer", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyHeader, key)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", userID))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name           string
+		userID         string
+		key            string
+		body           string
+		expectedStatus int
+		expectedCalls  int
+		expectedBody   string
+	}{
+		// ✅ First request runs the handler
+		{"First request", "alice", "k1", `{"amount":10}`, http.StatusOK, 1, `{"call":1,"key":"alice:k1"}`},
+		// ✅ Retry replays the stored response
+		{"Retry with same body", "alice", "k1", `{"amount":10}`, http.StatusOK, 1, `{"call":1,"key":"alice:k1"}`},
+		// ❌ Reused key with a different body
+		{"Reuse with different body", "alice", "k1", `{"amount":99}`, http.StatusUnprocessableEntity, 1, ""},
+		// ✅ Same key from another user is independent
+		{"Same key, other user", "bob", "k1", `{"amount":10}`, http.StatusOK, 2, `{"call":2,"key":"bob:k1"}`},
+		// ✅ No key always runs the handler
+		{"No key", "alice", "", `{"amount":10}`, http.StatusOK, 3, `{"call":3,"key":""}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			rec := send(tc.userID, tc.key, tc.body)
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("Expected handler to have run %d times, got %d", tc.expectedCalls, calls)
+			}
+			if tc.expectedBody != "" && rec.Body.String() != tc.expectedBody {
+				t.Errorf("Expected body %s, got %s", tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestIdempotencyRetry tests that failed and abandoned requests can be
+// retried with the same key
+func TestIdempotencyRetry(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	calls, status := 0, http.StatusOK
+	handler := Idempotency(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call":%d}`, calls)
+	}))
+
+	const body = `{"amount":10}`
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/ammount-transfer", strings.NewReader(body))
+		req.Header.Set(IdempotencyHeader, key)
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", "alice"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Keys left by requests that never finished
+	expired, live := time.Now().Add(-time.Second), time.Now().Add(time.Hour)
+	for key, leaseUntil := range map[string]*time.Time{"abandoned": &expired, "legacy": nil, "running": &live} {
+		record := models.IdempotencyKey{UserID: "alice", Key: key, Method: "POST", Path: "/api/ammount-transfer",
+			Fingerprint: fingerprint("POST", "/api/ammount-transfer", []byte(body)), LeaseUntil: leaseUntil}
+		if err := db.Create(&record).Error; err != nil {
+			t.Fatalf("Failed to create idempotency key: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		key            string
+		handlerStatus  int
+		expectedStatus int
+		expectedCalls  int
+	}{
+		// ❌ Server errors are not stored
+		{"Server error", "k1", http.StatusInternalServerError, http.StatusInternalServerError, 1},
+		// ✅ So the retry runs the handler again
+		{"Retry after server error", "k1", http.StatusOK, http.StatusOK, 2},
+		{"Replay after success", "k1", http.StatusOK, http.StatusOK, 2},
+		// ✅ Client errors are stored and replayed
+		{"Client error", "k2", http.StatusBadRequest, http.StatusBadRequest, 3},
+		{"Replay client error", "k2", http.StatusOK, http.StatusBadRequest, 3},
+		// ✅ An abandoned request is taken over once its lease has expired
+		{"Expired lease", "abandoned", http.StatusOK, http.StatusOK, 4},
+		{"Key without lease", "legacy", http.StatusOK, http.StatusOK, 5},
+		// ❌ A request still holding its lease is not run twice
+		{"Live lease", "running", http.StatusOK, http.StatusConflict, 5},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			status = tc.handlerStatus
+			rec := send(tc.key)
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("Expected handler to have run %d times, got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+// TestIdempotencyOverlap tests that a request running past the lease keeps
+// its key, and that a request whose key was taken over does not store its
+// result
+func TestIdempotencyOverlap(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.IdempotencyKey{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // Every connection to file::memory: is a new database
+
+	lease := IdempotencyLease
+	IdempotencyLease = 30 * time.Millisecond
+	defer func() { IdempotencyLease = lease }()
+
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := Idempotency(db)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := atomic.AddInt32(&calls, 1)
+		if r.URL.Query().Get("slow") == "true" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"call":%d}`, call)
+	}))
+
+	send := func(key, url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", url, strings.NewReader(`{"amount":10}`))
+		req.Header.Set(IdempotencyHeader, key)
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", "alice"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	sendSlow := func(key string) <-chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() { done <- send(key, "/api/ammount-transfer?slow=true") }()
+		<-started
+		return done
+	}
+
+	// ✅ A retry long after the lease would have run out still finds the key held
+	first := sendSlow("k1")
+	time.Sleep(5 * IdempotencyLease)
+	if rec := send("k1", "/api/ammount-transfer"); rec.Code != http.StatusConflict {
+		t.Errorf("Expected the overlapping retry to get 409, got %d", rec.Code)
+	}
+	release <- struct{}{}
+	if rec := <-first; rec.Code != http.StatusOK || rec.Body.String() != `{"call":1}` {
+		t.Errorf("Expected the first request to succeed, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := send("k1", "/api/ammount-transfer"); rec.Body.String() != `{"call":1}` {
+		t.Errorf("Expected the first response to be replayed, got %s", rec.Body.String())
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("Expected the handler to have run once, got %d", calls)
+	}
+
+	// ❌ A request that lost its key to a retry throws its result away
+	second := sendSlow("k2")
+	db.Model(&models.IdempotencyKey{}).Where("key = ?", "k2").Update("lease_token", "retry")
+	release <- struct{}{}
+	<-second
+	var stored models.IdempotencyKey
+	db.Where("key = ?", "k2").First(&stored)
+	if stored.Completed() || stored.LeaseToken != "retry" {
+		t.Errorf("Expected the stale result to be discarded, got %+v", stored)
+	}
+}
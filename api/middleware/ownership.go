@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// AccountResolver extracts the account number a request acts on. An empty
+// result means the request names no account; the handler decides whether
+// that is valid.
+type AccountResolver func(r *http.Request) (string, error)
+
+// AccountFromQuery reads the account number from a query parameter.
+func AccountFromQuery(param string) AccountResolver {
+	return func(r *http.Request) (string, error) {
+		return r.URL.Query().Get(param), nil
+	}
+}
+
+// AccountFromPath reads the account number from a route variable.
+func AccountFromPath(name string) AccountResolver {
+	return func(r *http.Request) (string, error) {
+		return mux.Vars(r)[name], nil
+	}
+}
+
+// AccountFromBody reads the account number from a field of the JSON body.
+// The body is restored so the handler can decode it again.
+func AccountFromBody(field string) AccountResolver {
+	return func(r *http.Request) (string, error) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", err
+		}
+		r.Body = io.NopCloser(bytes.NewBuffer(body)) // Restore body for the handler
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return "", nil // Malformed bodies are rejected by the handler
+		}
+		var accountNumber string
+		json.Unmarshal(fields[field], &accountNumber)
+		return accountNumber, nil
+	}
+}
+
+// UserIDFromContext returns the caller set by AuthMiddleware, or "" if the
+// request is not authenticated.
+func UserIDFromContext(r *http.Request) string {
+	userID, _ := r.Context().Value("user_id").(string)
+	return userID
+}
+
+// OwnsAccounts reports whether every listed account belongs to the user.
+// Empty account numbers are ignored. Closed accounts still count, so their
+// history stays readable; money-moving code rejects them separately.
+func OwnsAccounts(db *gorm.DB, userID string, accountNumbers ...string) (bool, error) {
+	numbers := uniqueAccounts(accountNumbers)
+	if len(numbers) == 0 {
+		return true, nil
+	}
+
+	var owned int64
+	err := db.Unscoped().Model(&models.Account{}).
+		Where("user_id = ? AND account_number IN ?", userID, numbers).
+		Count(&owned).Error
+	return owned == int64(len(numbers)), err
+}
+
+// OwnsAnyAccount reports whether at least one of the listed accounts belongs
+// to the user, e.g. either side of a transfer.
+func OwnsAnyAccount(db *gorm.DB, userID string, accountNumbers ...string) (bool, error) {
+	numbers := uniqueAccounts(accountNumbers)
+	if len(numbers) == 0 {
+		return false, nil
+	}
+
+	var owned int64
+	err := db.Unscoped().Model(&models.Account{}).
+		Where("user_id = ? AND account_number IN ?", userID, numbers).
+		Count(&owned).Error
+	return owned > 0, err
+}
+
+// OwnedAccountNumbers lists the account numbers belonging to the user,
+// including closed ones.
+func OwnedAccountNumbers(db *gorm.DB, userID string) ([]string, error) {
+	var numbers []string
+	err := db.Unscoped().Model(&models.Account{}).Where("user_id = ?", userID).Pluck("account_number", &numbers).Error
+	return numbers, err
+}
+
+// RequireAccountOwner rejects requests for an account that does not belong
+// to the authenticated caller. Foreign and missing accounts get the same 404
+// so account numbers cannot be probed. It must run after AuthMiddleware.
+func RequireAccountOwner(db *gorm.DB, resolve AccountResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := UserIDFromContext(r)
+			if userID == "" {
+				utils.SendResponse(w, http.StatusUnauthorized, false, "", nil, "Invalid Authorization")
+				return
+			}
+
+			accountNumber, err := resolve(r)
+			if err != nil {
+				utils.SendResponse(w, http.StatusBadRequest, false, "", nil, "Failed to read request")
+				return
+			}
+
+			owned, err := OwnsAccounts(db, userID, accountNumber)
+			if err != nil {
+				log.Println("Failed to check account ownership:", err)
+				utils.SendResponse(w, http.StatusInternalServerError, false, "", nil, "Failed to check account ownership")
+				return
+			}
+			if !owned {
+				utils.SendResponse(w, http.StatusNotFound, false, "", nil, "Account not found")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// uniqueAccounts drops empty and repeated account numbers
+func uniqueAccounts(accountNumbers []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, number := range accountNumbers {
+		if number != "" && !seen[number] {
+			seen[number] = true
+			result = append(result, number)
+		}
+	}
+	return result
+}
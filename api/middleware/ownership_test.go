@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupOwnershipDB opens an in-memory database with one account each for alice and bob
+func setupOwnershipDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Account{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	for userID, number := range map[string]string{"alice": "1111111111", "bob": "2222222222"} {
+		account := models.Account{UserID: userID, AccountNumber: number, OwnerName: userID, AccountType: "Savings", Currency: "USD"}
+		if err := db.Create(&account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+	return db
+}
+
+// TestRequireAccountOwner tests that callers can only act on their own accounts
+func TestRequireAccountOwner(t *testing.T) {
+	db := setupOwnershipDB(t)
+
+	var handlerBody string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handlerBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	})
+	byQuery := RequireAccountOwner(db, AccountFromQuery("account_number"))(next)
+	byBody := RequireAccountOwner(db, AccountFromBody("source_account"))(next)
+
+	tests := []struct {
+		name           string
+		handler        http.Handler
+		userID         string
+		url            string
+		body           string
+		expectedStatus int
+	}{
+		// ✅ Own account
+		{"Own account by query", byQuery, "alice", "/api/account-details?account_number=1111111111", "", http.StatusOK},
+		{"Own account by body", byBody, "alice", "/api/ammount-transfer", `{"source_account":"1111111111","destination_account":"2222222222"}`, http.StatusOK},
+		// ❌ Another user's account
+		{"Other user's account by query", byQuery, "alice", "/api/account-details?account_number=2222222222", "", http.StatusNotFound},
+		{"Other user's account by body", byBody, "alice", "/api/ammount-transfer", `{"source_account":"2222222222","destination_account":"1111111111"}`, http.StatusNotFound},
+		{"Other user's history", byQuery, "bob", "/api/transaction/history?account_number=1111111111", "", http.StatusNotFound},
+		// ❌ Unknown account looks the same as a foreign one
+		{"Unknown account", byQuery, "alice", "/api/account-details?account_number=9999999999", "", http.StatusNotFound},
+		// ❌ Not authenticated
+		{"No caller", byQuery, "", "/api/account-details?account_number=1111111111", "", http.StatusUnauthorized},
+		// ✅ No account named; the handler decides
+		{"No account", byQuery, "alice", "/api/transaction/history", "", http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			handlerBody = ""
+			req := httptest.NewRequest("POST", tc.url, strings.NewReader(tc.body))
+			if tc.userID != "" {
+				req = req.WithContext(context.WithValue(req.Context(), "user_id", tc.userID))
+			}
+			rec := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if rec.Code == http.StatusOK && handlerBody != tc.body {
+				t.Errorf("Expected handler to receive the original body %q, got %q", tc.body, handlerBody)
+			}
+		})
+	}
+}
+
+// TestOwnsAnyAccount tests access to transactions with several parties
+func TestOwnsAnyAccount(t *testing.T) {
+	db := setupOwnershipDB(t)
+
+	tests := []struct {
+		name     string
+		userID   string
+		accounts []string
+		expected bool
+	}{
+		{"Sender of a transfer", "alice", []string{"1111111111", "2222222222"}, true},
+		{"Recipient of a transfer", "bob", []string{"1111111111", "2222222222"}, true},
+		{"Unrelated user", "mallory", []string{"1111111111", "2222222222"}, false},
+		{"Deposit into another user's account", "alice", []string{"2222222222", ""}, false},
+		{"No accounts", "alice", []string{"", ""}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			owned, err := OwnsAnyAccount(db, tc.userID, tc.accounts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if owned != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, owned)
+			}
+		})
+	}
+}
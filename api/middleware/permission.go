@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+)
+
+// RoleFromContext returns the caller's role set by AuthMiddleware, or "" if
+// the request is not authenticated.
+func RoleFromContext(r *http.Request) string {
+	role, _ := r.Context().Value("role").(string)
+	return role
+}
+
+// RequirePermission rejects callers whose role does not grant the
+// permission. Roles come from the token, so a role change takes effect at
+// the user's next token refresh. It must run after AuthMiddleware.
+func RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if UserIDFromContext(r) == "" {
+				utils.SendResponse(w, http.StatusUnauthorized, false, "", nil, "Invalid Authorization")
+				return
+			}
+			if !models.HasPermission(RoleFromContext(r), permission) {
+				utils.SendResponse(w, http.StatusForbidden, false, "", nil, "Insufficient permissions")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+)
+
+// TestRequirePermission tests that staff routes reject callers without the permission
+func TestRequirePermission(t *testing.T) {
+	handler := RequirePermission(models.PermissionReverseTransactions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		userID         string
+		role           string
+		expectedStatus int
+	}{
+		// ✅ Role with the permission
+		{"Admin", "admin-1", models.RoleAdmin, http.StatusOK},
+		// ❌ Roles without it
+		{"Teller", "teller-1", models.RoleTeller, http.StatusForbidden},
+		{"Customer", "alice", models.RoleCustomer, http.StatusForbidden},
+		{"No role", "alice", "", http.StatusForbidden},
+		// ❌ Not authenticated
+		{"No caller", "", models.RoleAdmin, http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			req := httptest.NewRequest("POST", "/api/admin/transactions/abc/reverse", nil)
+			ctx := context.WithValue(req.Context(), "role", tc.role)
+			if tc.userID != "" {
+				ctx = context.WithValue(ctx, "user_id", tc.userID)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req.WithContext(ctx))
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+		})
+	}
+}
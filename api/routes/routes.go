@@ -1,8 +1,16 @@
 package routes
 
 import (
+	"log"
+	"net/http"
+
 	"github.com/ashil-poojary/banking-ledger-service/api/handlers"
 	"github.com/ashil-poojary/banking-ledger-service/api/middleware"
+	"github.com/ashil-poojary/banking-ledger-service/auth"
+	"github.com/ashil-poojary/banking-ledger-service/config"
+	"github.com/ashil-poojary/banking-ledger-service/ledger"
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/ashil-poojary/banking-ledger-service/session"
 	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
 	"github.com/streadway/amqp"
@@ -11,31 +19,88 @@ import (
 )
 
 // SetupRoutes initializes API routes
-func SetupRoutes(r *mux.Router, postgresDB *gorm.DB, mongoDB *mongo.Database, redisClient *redis.Client, rabbitMQChannel *amqp.Channel) {
-	accountHandler := handlers.NewAccountHandler(postgresDB)
-	transactionHandler := handlers.NewTransactionHandler(postgresDB, mongoDB, rabbitMQChannel, "transactions")
-	authHandler := handlers.NewAuthHandler(postgresDB, redisClient)
+func SetupRoutes(r *mux.Router, postgresDB *gorm.DB, mongoDB *mongo.Database, redisClient *redis.Client, rabbitMQChannel *amqp.Channel, queueName string, fxRates ledger.FXRateProvider) {
+	accountHandler := handlers.NewAccountHandler(postgresDB, fxRates)
+	transactionHandler := handlers.NewTransactionHandler(postgresDB, mongoDB, rabbitMQChannel, queueName, fxRates)
+	sessions := session.NewStore(redisClient, config.GetEnvDuration("REFRESH_TOKEN_TTL", session.DefaultRefreshTTL))
+	denylist := session.NewDenylist(redisClient)
+	throttle := auth.NewLoginThrottle(redisClient)
+	throttle.MaxUserFailures = int64(config.GetEnvInt("LOGIN_MAX_FAILURES", auth.DefaultMaxUserFailures))
+	throttle.MaxIPFailures = int64(config.GetEnvInt("LOGIN_MAX_IP_FAILURES", auth.DefaultMaxIPFailures))
+	throttle.Lockout = config.GetEnvDuration("LOGIN_LOCKOUT", auth.DefaultLockout)
+	authHandler := handlers.NewAuthHandler(postgresDB, redisClient, sessions, denylist, throttle)
+	adminHandler := handlers.NewAdminHandler(postgresDB, mongoDB, sessions, denylist)
 
 	r.Use(middleware.LoggingMiddleware)
 
 	// Auth Routes
 	r.HandleFunc("/api/register", authHandler.Register).Methods("POST")
 	r.HandleFunc("/api/login", authHandler.Login).Methods("POST")
+	r.HandleFunc("/api/login/mfa", authHandler.LoginMFA).Methods("POST")
 	r.HandleFunc("/api/logout", authHandler.Logout).Methods("POST")
+	r.HandleFunc("/api/token/refresh", authHandler.RefreshToken).Methods("POST")
+	r.HandleFunc("/.well-known/jwks.json", authHandler.JWKS).Methods("GET")
 
 	// Secure account & transaction routes with middleware
 	protected := r.PathPrefix("/api").Subrouter()
-	protected.Use(middleware.AuthMiddleware(redisClient))
+	protected.Use(middleware.AuthMiddleware(sessions, denylist))
+
+	// Session Routes
+	protected.HandleFunc("/sessions", authHandler.ListSessions).Methods("GET")
+	protected.HandleFunc("/sessions/{id}", authHandler.RevokeSession).Methods("DELETE")
+	protected.HandleFunc("/password", authHandler.ChangePassword).Methods("PUT")
+
+	// MFA Routes
+	protected.HandleFunc("/mfa/totp", authHandler.EnrollMFA).Methods("POST")
+	protected.HandleFunc("/mfa/totp/confirm", authHandler.ConfirmMFA).Methods("POST")
+	protected.HandleFunc("/mfa/totp", authHandler.DisableMFA).Methods("DELETE")
+	protected.HandleFunc("/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes).Methods("POST")
+
+	// Account-scoped routes only act on accounts owned by the caller
+	ownsQueryAccount := middleware.RequireAccountOwner(postgresDB, middleware.AccountFromQuery("account_number"))
+	ownsBodyAccount := middleware.RequireAccountOwner(postgresDB, middleware.AccountFromBody("account_number"))
+	ownsSourceAccount := middleware.RequireAccountOwner(postgresDB, middleware.AccountFromBody("source_account"))
+	ownsPathAccount := middleware.RequireAccountOwner(postgresDB, middleware.AccountFromPath("number"))
+
+	// Routes that move money, and every staff route, can be limited to
+	// sessions signed in with MFA
+	mfaRequired := config.GetEnvBool("MFA_REQUIRED_FOR_PAYMENTS", true)
+	if !mfaRequired {
+		log.Println("MFA_REQUIRED_FOR_PAYMENTS is off, payments do not need a second factor; use this only in development")
+	}
+	mfa := middleware.RequireMFA(mfaRequired)
 
 	// Account Routes
 	protected.HandleFunc("/create-account", accountHandler.CreateAccount).Methods("POST")
 	protected.HandleFunc("/get-user-accounts", accountHandler.GetUserAccounts).Methods("GET")
-	protected.HandleFunc("/account-details", accountHandler.GetAccount).Methods("GET")
-	protected.HandleFunc("/update-account", accountHandler.UpdateAccount).Methods("PUT")
-	protected.HandleFunc("/delete-account", accountHandler.DeleteAccount).Methods("DELETE")
+	protected.Handle("/account-details", ownsQueryAccount(http.HandlerFunc(accountHandler.GetAccount))).Methods("GET")
+	protected.Handle("/update-account", ownsQueryAccount(http.HandlerFunc(accountHandler.UpdateAccountByQuery))).Methods("PUT")
+	protected.Handle("/accounts/{number}", ownsPathAccount(http.HandlerFunc(accountHandler.UpdateAccount))).Methods("PATCH")
+	protected.Handle("/delete-account", mfa(ownsQueryAccount(http.HandlerFunc(accountHandler.DeleteAccount)))).Methods("DELETE")
+	protected.Handle("/accounts/{number}/balance", ownsPathAccount(http.HandlerFunc(accountHandler.GetBalance))).Methods("GET")
+	protected.Handle("/accounts/{number}/statement", ownsPathAccount(http.HandlerFunc(accountHandler.GetStatement))).Methods("GET")
+
+	// Money-moving routes honour the Idempotency-Key header
+	idempotent := middleware.Idempotency(postgresDB)
 
 	// Transaction Routes
-	protected.HandleFunc("/ammount-transfer", transactionHandler.TransferFunds).Methods("POST")
-	protected.HandleFunc("/transaction/history", transactionHandler.GetTransactionHistory).Methods("GET")
-	protected.HandleFunc("/transaction", transactionHandler.GetTransaction).Methods("GET")
+	protected.Handle("/ammount-transfer", mfa(ownsSourceAccount(idempotent(http.HandlerFunc(transactionHandler.TransferFunds))))).Methods("POST")
+	protected.Handle("/deposit", mfa(ownsBodyAccount(idempotent(http.HandlerFunc(transactionHandler.Deposit))))).Methods("POST")
+	protected.Handle("/withdraw", mfa(ownsBodyAccount(idempotent(http.HandlerFunc(transactionHandler.Withdraw))))).Methods("POST")
+	protected.HandleFunc("/transactions/{id}", transactionHandler.GetTransactionByID).Methods("GET")
+	protected.Handle("/transaction/history", ownsQueryAccount(http.HandlerFunc(transactionHandler.GetTransactionHistory))).Methods("GET")
+	protected.Handle("/transaction", ownsQueryAccount(http.HandlerFunc(transactionHandler.GetTransaction))).Methods("GET")
+
+	// Staff routes act across users and are restricted by permission
+	admin := protected.PathPrefix("/admin").Subrouter()
+	admin.Use(mfa) // A stolen staff password alone must not reverse payments or change roles
+	admin.Handle("/accounts", middleware.RequirePermission(models.PermissionSearchAccounts)(http.HandlerFunc(adminHandler.SearchAccounts))).Methods("GET")
+	admin.Handle("/accounts/{number}/freeze", middleware.RequirePermission(models.PermissionFreezeAccounts)(http.HandlerFunc(adminHandler.FreezeAccount))).Methods("POST")
+	admin.Handle("/accounts/{number}/unfreeze", middleware.RequirePermission(models.PermissionFreezeAccounts)(http.HandlerFunc(adminHandler.UnfreezeAccount))).Methods("POST")
+	admin.Handle("/accounts/{number}/status", middleware.RequirePermission(models.PermissionManageAccounts)(http.HandlerFunc(adminHandler.SetAccountStatus))).Methods("PUT")
+	admin.Handle("/accounts/{number}/status-history", middleware.RequirePermission(models.PermissionSearchAccounts)(http.HandlerFunc(adminHandler.GetAccountStatusHistory))).Methods("GET")
+	admin.Handle("/transactions/{id}/reverse", middleware.RequirePermission(models.PermissionReverseTransactions)(http.HandlerFunc(adminHandler.ReverseTransaction))).Methods("POST")
+	admin.Handle("/users/{id}/role", middleware.RequirePermission(models.PermissionManageUsers)(http.HandlerFunc(adminHandler.SetUserRole))).Methods("PUT")
+	admin.Handle("/users/{id}/sessions", middleware.RequirePermission(models.PermissionManageUsers)(http.HandlerFunc(adminHandler.RevokeUserSessions))).Methods("DELETE")
+	admin.Handle("/tokens/revoke", middleware.RequirePermission(models.PermissionManageUsers)(http.HandlerFunc(adminHandler.RevokeToken))).Methods("POST")
 }
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Limits for MFA challenges
+const (
+	ChallengeTTL         = 5 * time.Minute
+	MaxChallengeAttempts = 5
+)
+
+// ErrInvalidChallenge is returned for unknown, expired or exhausted challenges
+var ErrInvalidChallenge = errors.New("invalid or expired MFA challenge")
+
+// MFAChallenges tracks logins that passed the password check and are waiting
+// for a second factor. A challenge allows a few code attempts, then expires.
+type MFAChallenges struct {
+	Redis *redis.Client
+}
+
+// NewMFAChallenges creates the challenge store
+func NewMFAChallenges(client *redis.Client) *MFAChallenges {
+	return &MFAChallenges{Redis: client}
+}
+
+func challengeKey(token string) string         { return "mfa-challenge:" + hashToken(token) }
+func challengeAttemptsKey(token string) string { return "mfa-challenge-attempts:" + hashToken(token) }
+
+// Create starts a challenge for a user and returns its token
+func (c *MFAChallenges) Create(ctx context.Context, userID string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	if err := c.Redis.Set(ctx, challengeKey(token), userID, ChallengeTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// User returns the user a challenge belongs to
+func (c *MFAChallenges) User(ctx context.Context, token string) (string, error) {
+	userID, err := c.Redis.Get(ctx, challengeKey(token)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrInvalidChallenge
+	}
+	return userID, err
+}
+
+// Fail records a wrong code and ends the challenge after too many
+func (c *MFAChallenges) Fail(ctx context.Context, token string) error {
+	pipe := c.Redis.TxPipeline()
+	attempts := pipe.Incr(ctx, challengeAttemptsKey(token))
+	pipe.Expire(ctx, challengeAttemptsKey(token), ChallengeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if attempts.Val() >= MaxChallengeAttempts {
+		return c.Complete(ctx, token)
+	}
+	return nil
+}
+
+// Complete ends a challenge so its token cannot be used again
+func (c *MFAChallenges) Complete(ctx context.Context, token string) error {
+	return c.Redis.Del(ctx, challengeKey(token), challengeAttemptsKey(token)).Err()
+}
+
+// hashToken is what is stored in place of a challenge token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeCount is how many recovery codes a user gets at a time
+const RecoveryCodeCount = 10
+
+// MFA errors
+var (
+	ErrMFANotEnabled     = errors.New("MFA is not enabled")
+	ErrMFAAlreadyEnabled = errors.New("MFA is already enabled")
+	ErrInvalidMFACode    = errors.New("invalid MFA code")
+)
+
+// StartEnrollment gives the user a new, unconfirmed TOTP secret. Starting
+// again before confirming replaces the secret.
+func StartEnrollment(db *gorm.DB, userID string) (*models.MFAFactor, error) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var factor models.MFAFactor
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userID).First(&factor).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			factor = models.MFAFactor{UserID: userID, Secret: secret}
+			return tx.Create(&factor).Error
+		case err != nil:
+			return err
+		case factor.Confirmed():
+			return ErrMFAAlreadyEnabled
+		}
+		factor.Secret = secret
+		return tx.Model(&factor).Update("secret", secret).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &factor, nil
+}
+
+// ConfirmEnrollment turns MFA on once the user proves their authenticator
+// works, and returns their recovery codes.
+func ConfirmEnrollment(db *gorm.DB, userID, code string, now time.Time) ([]string, error) {
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var factor models.MFAFactor
+		err := tx.Where("user_id = ?", userID).First(&factor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMFANotEnabled
+		}
+		if err != nil {
+			return err
+		}
+		if factor.Confirmed() {
+			return ErrMFAAlreadyEnabled
+		}
+
+		step, ok := ValidateTOTP(factor.Secret, code, now)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		err = tx.Model(&factor).Updates(map[string]interface{}{"confirmed_at": now, "last_used_step": step}).Error
+		if err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// Enabled reports whether the user has confirmed MFA
+func Enabled(db *gorm.DB, userID string) (bool, error) {
+	var count int64
+	err := db.Model(&models.MFAFactor{}).Where("user_id = ? AND confirmed_at IS NOT NULL", userID).Count(&count).Error
+	return count > 0, err
+}
+
+// VerifyCode checks a TOTP code or an unused recovery code. Each is
+// accepted only once.
+func VerifyCode(db *gorm.DB, userID, code string, now time.Time) error {
+	var factor models.MFAFactor
+	err := db.Where("user_id = ? AND confirmed_at IS NOT NULL", userID).First(&factor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+
+	if step, ok := ValidateTOTP(factor.Secret, code, now); ok {
+		// Only move forward, so a code seen once cannot be used again
+		result := db.Model(&models.MFAFactor{}).
+			Where("id = ? AND last_used_step < ?", factor.ID, step).
+			Update("last_used_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all of the user's recovery codes
+func RegenerateRecoveryCodes(db *gorm.DB, userID string) ([]string, error) {
+	if enabled, err := Enabled(db, userID); err != nil || !enabled {
+		if err == nil {
+			err = ErrMFANotEnabled
+		}
+		return nil, err
+	}
+
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// Disable removes the user's authenticator and recovery codes
+func Disable(db *gorm.DB, userID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userID).Delete(&models.MFAFactor{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMFANotEnabled
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// replaceRecoveryCodes stores new recovery codes in place of the old ones
+// and returns them in plain text, the only time they are available
+func replaceRecoveryCodes(tx *gorm.DB, userID string) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	records := make([]models.RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns a random code like "k3m9p-x2q7w" (50 bits)
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32NoPadding.EncodeToString(raw))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode hashes a recovery code as typed, ignoring case, spaces
+// and dashes. The codes are random enough that a fast hash is safe.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.MFAFactor{}, &models.RecoveryCode{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+// enroll turns MFA on for a user and returns its secret and recovery codes
+func enroll(t *testing.T, db *gorm.DB, userID string, now time.Time) (string, []string) {
+	factor, err := StartEnrollment(db, userID)
+	if err != nil {
+		t.Fatalf("Failed to start enrollment: %v", err)
+	}
+	code, _ := TOTPCode(factor.Secret, TOTPStep(now))
+	codes, err := ConfirmEnrollment(db, userID, code, now)
+	if err != nil {
+		t.Fatalf("Failed to confirm enrollment: %v", err)
+	}
+	return factor.Secret, codes
+}
+
+// TestEnrollment tests that MFA is only on once confirmed
+func TestEnrollment(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Now()
+
+	factor, err := StartEnrollment(db, "user-1")
+	if err != nil {
+		t.Fatalf("Failed to start enrollment: %v", err)
+	}
+	if enabled, _ := Enabled(db, "user-1"); enabled {
+		t.Error("Expected MFA to stay off until confirmed")
+	}
+	if _, err := ConfirmEnrollment(db, "user-1", "000000", now); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("Expected a wrong code to be rejected, got %v", err)
+	}
+
+	code, _ := TOTPCode(factor.Secret, TOTPStep(now))
+	codes, err := ConfirmEnrollment(db, "user-1", code, now)
+	if err != nil || len(codes) != RecoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %d (%v)", RecoveryCodeCount, len(codes), err)
+	}
+	if enabled, _ := Enabled(db, "user-1"); !enabled {
+		t.Error("Expected MFA to be on after confirmation")
+	}
+	if _, err := StartEnrollment(db, "user-1"); !errors.Is(err, ErrMFAAlreadyEnabled) {
+		t.Errorf("Expected a second enrollment to be refused, got %v", err)
+	}
+
+	// Only hashes are stored
+	var stored models.RecoveryCode
+	db.First(&stored)
+	for _, code := range codes {
+		if stored.CodeHash == code {
+			t.Error("Expected recovery codes to be stored hashed")
+		}
+	}
+}
+
+// TestVerifyCode tests that TOTP and recovery codes work once each
+func TestVerifyCode(t *testing.T) {
+	db := setupTestDB(t)
+	enrolledAt := time.Now().Add(-time.Hour)
+	secret, codes := enroll(t, db, "user-1", enrolledAt)
+
+	now := time.Now()
+	current, _ := TOTPCode(secret, TOTPStep(now))
+
+	tests := []struct {
+		name        string
+		userID      string
+		code        string
+		expectedErr error
+	}{
+		// ✅ Current TOTP code
+		{"TOTP code", "user-1", current, nil},
+		// ❌ The same code again
+		{"Replayed TOTP code", "user-1", current, ErrInvalidMFACode},
+		// ✅ Recovery code, typed loosely
+		{"Recovery code", "user-1", " " + codes[0] + " ", nil},
+		// ❌ Recovery codes are single use
+		{"Used recovery code", "user-1", codes[0], ErrInvalidMFACode},
+		// ❌ Wrong code
+		{"Wrong code", "user-1", "123-456", ErrInvalidMFACode},
+		// ❌ Another user's recovery code
+		{"Other user", "user-2", codes[1], ErrMFANotEnabled},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			if err := VerifyCode(db, tc.userID, tc.code, now); !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+// TestRegenerateAndDisable tests replacing recovery codes and turning MFA off
+func TestRegenerateAndDisable(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Now()
+	_, oldCodes := enroll(t, db, "user-1", now)
+
+	newCodes, err := RegenerateRecoveryCodes(db, "user-1")
+	if err != nil || len(newCodes) != RecoveryCodeCount {
+		t.Fatalf("Expected %d new recovery codes, got %d (%v)", RecoveryCodeCount, len(newCodes), err)
+	}
+	if err := VerifyCode(db, "user-1", oldCodes[0], now); !errors.Is(err, ErrInvalidMFACode) {
+		t.Errorf("Expected old recovery codes to stop working, got %v", err)
+	}
+	if err := VerifyCode(db, "user-1", newCodes[0], now); err != nil {
+		t.Errorf("Expected new recovery codes to work, got %v", err)
+	}
+
+	if err := Disable(db, "user-1"); err != nil {
+		t.Fatalf("Failed to disable MFA: %v", err)
+	}
+	if enabled, _ := Enabled(db, "user-1"); enabled {
+		t.Error("Expected MFA to be off")
+	}
+	var remaining int64
+	db.Model(&models.RecoveryCode{}).Where("user_id = ?", "user-1").Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected recovery codes to be deleted, %d left", remaining)
+	}
+	if _, err := RegenerateRecoveryCodes(db, "user-1"); !errors.Is(err, ErrMFANotEnabled) {
+		t.Errorf("Expected regeneration without MFA to fail, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Package auth holds the checks around signing in: throttling of password
+// guesses and second factors.
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Defaults for LoginThrottle
+const (
+	DefaultMaxUserFailures = 5
+	DefaultMaxIPFailures   = 20
+	DefaultFailureWindow   = 15 * time.Minute
+	DefaultLockout         = 15 * time.Minute
+)
+
+// Delays before evaluating a login, by earlier failures
+const (
+	freeFailures = 2 // Failures allowed before any delay
+	baseDelay    = 500 * time.Millisecond
+	maxDelay     = 8 * time.Second
+)
+
+// LoginThrottle counts failed logins per username and per IP in Redis. Each
+// failure slows the next attempt down further, and too many failures lock
+// the username (or IP) out for a while. Usernames are counted whether or not
+// they exist, so the throttle does not reveal which ones do.
+type LoginThrottle struct {
+	Redis           *redis.Client
+	MaxUserFailures int64
+	MaxIPFailures   int64
+	Window          time.Duration // How long failures are remembered
+	Lockout         time.Duration
+}
+
+// NewLoginThrottle creates a throttle with the default limits
+func NewLoginThrottle(client *redis.Client) *LoginThrottle {
+	return &LoginThrottle{
+		Redis:           client,
+		MaxUserFailures: DefaultMaxUserFailures,
+		MaxIPFailures:   DefaultMaxIPFailures,
+		Window:          DefaultFailureWindow,
+		Lockout:         DefaultLockout,
+	}
+}
+
+func failuresKey(kind, id string) string { return "login-failures:" + kind + ":" + id }
+func lockoutKey(kind, id string) string  { return "login-lockout:" + kind + ":" + id }
+
+// normalizeUsername makes "Alice" and "alice " share a counter
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Check returns how long the caller must wait before the attempt is
+// evaluated, or, if the username or IP is locked out, how long until the
+// lockout ends.
+func (t *LoginThrottle) Check(ctx context.Context, username, ip string) (delay, lockedFor time.Duration, err error) {
+	username = normalizeUsername(username)
+
+	pipe := t.Redis.Pipeline()
+	userLock := pipe.PTTL(ctx, lockoutKey("user", username))
+	ipLock := pipe.PTTL(ctx, lockoutKey("ip", ip))
+	userFailures := pipe.Get(ctx, failuresKey("user", username))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+
+	if lockedFor = maxDuration(userLock.Val(), ipLock.Val()); lockedFor > 0 {
+		return 0, lockedFor, nil
+	}
+	failures, _ := userFailures.Int64()
+	return Delay(failures), 0, nil
+}
+
+// Fail records a failed attempt and locks the username or IP out once it
+// reaches its limit. It reports whether the attempt caused a lockout.
+func (t *LoginThrottle) Fail(ctx context.Context, username, ip string) (bool, error) {
+	username = normalizeUsername(username)
+
+	pipe := t.Redis.TxPipeline()
+	userFailures := pipe.Incr(ctx, failuresKey("user", username))
+	pipe.Expire(ctx, failuresKey("user", username), t.Window)
+	ipFailures := pipe.Incr(ctx, failuresKey("ip", ip))
+	pipe.Expire(ctx, failuresKey("ip", ip), t.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+
+	locked := false
+	if userFailures.Val() >= t.MaxUserFailures {
+		if err := t.lock(ctx, "user", username); err != nil {
+			return false, err
+		}
+		locked = true
+	}
+	if ipFailures.Val() >= t.MaxIPFailures {
+		if err := t.lock(ctx, "ip", ip); err != nil {
+			return false, err
+		}
+		locked = true
+	}
+	return locked, nil
+}
+
+// Succeed clears the username's failures after a successful login. The IP
+// counter is left alone, so an attacker cannot reset it by signing in to an
+// account of their own between guesses.
+func (t *LoginThrottle) Succeed(ctx context.Context, username string) error {
+	return t.Redis.Del(ctx, failuresKey("user", normalizeUsername(username))).Err()
+}
+
+// lock starts a lockout and forgets the failures that led to it
+func (t *LoginThrottle) lock(ctx context.Context, kind, id string) error {
+	_, err := t.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, lockoutKey(kind, id), 1, t.Lockout)
+		pipe.Del(ctx, failuresKey(kind, id))
+		return nil
+	})
+	return err
+}
+
+// Delay is the wait imposed after a number of recent failures: none for the
+// first few, then doubling up to a cap.
+func Delay(failures int64) time.Duration {
+	if failures <= freeFailures {
+		return 0
+	}
+	delay := baseDelay
+	for i := int64(freeFailures + 1); i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
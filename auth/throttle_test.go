@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// TestDelay tests the progressive delay after failed logins
+func TestDelay(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int64
+		expectedDelay time.Duration
+	}{
+		// ✅ First attempts are not slowed down
+		{"No failures", 0, 0},
+		{"Free failures", 2, 0},
+		// ✅ Then the delay doubles
+		{"Third failure", 3, 500 * time.Millisecond},
+		{"Fourth failure", 4, time.Second},
+		{"Sixth failure", 6, 4 * time.Second},
+		// ✅ Up to a cap
+		{"Many failures", 50, 8 * time.Second},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			if delay := Delay(tc.failures); delay != tc.expectedDelay {
+				t.Errorf("Expected delay %s, got %s", tc.expectedDelay, delay)
+			}
+		})
+	}
+}
+
+// TestLockout tests that repeated failures lock a username out. It needs a
+// real Redis server, so it only runs when TEST_REDIS_ADDR is set.
+func TestLockout(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+
+	ctx := context.Background()
+	throttle := NewLoginThrottle(redis.NewClient(&redis.Options{Addr: addr}))
+	throttle.MaxUserFailures = 3
+	username, ip := uuid.NewString(), uuid.NewString()
+
+	for i := 1; i <= 3; i++ {
+		locked, err := throttle.Fail(ctx, username, ip)
+		if err != nil {
+			t.Fatalf("Failed to record failure: %v", err)
+		}
+		if locked != (i == 3) {
+			t.Errorf("Failure %d: expected locked=%v, got %v", i, i == 3, locked)
+		}
+	}
+
+	// Locked regardless of how the username is written
+	if _, lockedFor, err := throttle.Check(ctx, " "+username+" ", "another-ip"); err != nil || lockedFor <= 0 {
+		t.Errorf("Expected the username to be locked out, got %s (%v)", lockedFor, err)
+	}
+
+	// Other usernames from the same IP are still allowed
+	if _, lockedFor, err := throttle.Check(ctx, uuid.NewString(), ip); err != nil || lockedFor > 0 {
+		t.Errorf("Expected another username to be allowed, got %s (%v)", lockedFor, err)
+	}
+}
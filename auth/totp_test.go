@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 test key "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestTOTPCode tests codes against the RFC 6238 SHA-1 test vectors
+// (truncated to 6 digits)
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		name         string
+		unix         int64
+		expectedCode string
+	}{
+		{"T=59", 59, "287082"},
+		{"T=1111111109", 1111111109, "081804"},
+		{"T=1234567890", 1234567890, "005924"},
+		{"T=20000000000", 20000000000, "353130"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tc.unix, 0)))
+			if err != nil || code != tc.expectedCode {
+				t.Errorf("Expected code %s, got %s (%v)", tc.expectedCode, code, err)
+			}
+		})
+	}
+}
+
+// TestValidateTOTP tests the accepted window around the current time
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	code, _ := TOTPCode(rfcSecret, TOTPStep(now))
+
+	tests := []struct {
+		name       string
+		code       string
+		at         time.Time
+		expectedOK bool
+	}{
+		// ✅ Current step
+		{"Current code", code, now, true},
+		// ✅ One step of clock drift either way
+		{"Previous step", code, now.Add(TOTPPeriod), true},
+		{"Next step", code, now.Add(-TOTPPeriod), true},
+		// ❌ Too old
+		{"Expired code", code, now.Add(3 * TOTPPeriod), false},
+		// ❌ Wrong or malformed
+		{"Wrong code", "000000", now, false},
+		{"Too short", code[:5], now, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			if _, ok := ValidateTOTP(rfcSecret, tc.code, tc.at); ok != tc.expectedOK {
+				t.Errorf("Expected ok=%v, got %v", tc.expectedOK, ok)
+			}
+		})
+	}
+}
+
+// TestProvisioningURI tests the URI given to authenticator apps
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Banking Ledger", "alice", rfcSecret)
+	for _, part := range []string{"otpauth://totp/Banking%20Ledger:alice?", "secret=" + rfcSecret, "issuer=Banking+Ledger", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("Expected %q in %s", part, uri)
+		}
+	}
+}
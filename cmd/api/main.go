@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/ashil-poojary/banking-ledger-service/api/routes"
 	"github.com/ashil-poojary/banking-ledger-service/config"
+	"github.com/ashil-poojary/banking-ledger-service/ledger"
 	"github.com/ashil-poojary/banking-ledger-service/storage"
+	"github.com/ashil-poojary/banking-ledger-service/utils"
 	"github.com/ashil-poojary/banking-ledger-service/worker"
 	"github.com/gorilla/mux"
 )
@@ -16,6 +19,13 @@ func main() {
 	// Load environment variables
 	config.LoadEnv()
 
+	// Refuse to issue tokens with a known secret
+	keys, err := utils.LoadKeyManager()
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	utils.SetKeyManager(keys)
+
 	// Initialize database connections
 	postgresDB := storage.InitPostgres()
 	mongoDB := storage.InitMongo()
@@ -25,12 +35,37 @@ func main() {
 	_, rabbitMQChannel := storage.InitRabbitMQ()
 	defer storage.CloseRabbitMQ()
 
-	// Start transaction worker in a goroutine
-	go worker.ProcessTransactions("transactions", postgresDB, mongoDB, rabbitMQChannel)
+	// Declare queues before anything publishes to them
+	workerConfig := worker.LoadConfig()
+	if err := worker.DeclareTopology(rabbitMQChannel, workerConfig.QueueName, workerConfig.RetryPolicy); err != nil {
+		log.Fatalf("Failed to declare RabbitMQ topology: %v", err)
+	}
+
+	// Start the outbox relay on its own confirm-mode channel
+	relayChannel, confirms := storage.OpenConfirmChannel()
+	go worker.NewOutboxRelay(postgresDB, relayChannel, confirms).Run(context.Background())
+
+	// Run the transaction worker in-process unless it is deployed separately (cmd/worker)
+	if config.GetEnvBool("RUN_EMBEDDED_WORKER", true) {
+		go func() {
+			if err := worker.ProcessTransactions(context.Background(), workerConfig, postgresDB, mongoDB, rabbitMQChannel); err != nil {
+				log.Fatalf("Worker failed: %v", err)
+			}
+		}()
+		if workerConfig.SnapshotInterval > 0 {
+			go worker.RunBalanceSnapshots(context.Background(), postgresDB, workerConfig.SnapshotInterval)
+		}
+	}
+
+	// Exchange rates for cross-currency transfers
+	fxRates, err := ledger.LoadFXRates()
+	if err != nil {
+		log.Fatalf("Failed to load FX rates: %v", err)
+	}
 
 	// Start API server
 	r := mux.NewRouter()
-	routes.SetupRoutes(r, postgresDB, mongoDB, redisClient, rabbitMQChannel)
+	routes.SetupRoutes(r, postgresDB, mongoDB, redisClient, rabbitMQChannel, workerConfig.QueueName, fxRates)
 
 	fmt.Println("API server running on port 8080")
 	log.Fatal(http.ListenAndServe(":8080", r))
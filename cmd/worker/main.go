@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/config"
+	"github.com/ashil-poojary/banking-ledger-service/storage"
+	"github.com/ashil-poojary/banking-ledger-service/worker"
+)
+
+func main() {
+	// Load environment variables
+	config.LoadEnv()
+	cfg := worker.LoadConfig()
+
+	// Stop consuming on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Initialize database connections
+	postgresDB := storage.InitPostgres()
+	mongoDB := storage.InitMongo()
+
+	// Initialize RabbitMQ
+	rabbitMQConn, rabbitMQChannel := storage.InitRabbitMQ()
+	defer storage.CloseRabbitMQ()
+
+	// Start health endpoint
+	var draining atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", worker.HealthHandler(postgresDB, mongoDB, rabbitMQConn, &draining))
+	healthPort := config.GetEnv("WORKER_HEALTH_PORT", "8081")
+	server := &http.Server{Addr: ":" + healthPort, Handler: mux}
+	go func() {
+		fmt.Println("Worker health endpoint running on port", healthPort)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Health server failed: %v", err)
+		}
+	}()
+
+	// Snapshot balances for point-in-time queries
+	if cfg.SnapshotInterval > 0 {
+		go worker.RunBalanceSnapshots(ctx, postgresDB, cfg.SnapshotInterval)
+	}
+
+	// Run until a shutdown signal arrives; returns after in-flight messages finish
+	go func() {
+		<-ctx.Done()
+		draining.Store(true)
+	}()
+	if err := worker.ProcessTransactions(ctx, cfg, postgresDB, mongoDB, rabbitMQChannel); err != nil {
+		log.Fatalf("Worker failed: %v", err)
+	}
+	if ctx.Err() == nil {
+		log.Fatal("RabbitMQ closed the consumer unexpectedly") // Exit non-zero so the worker is restarted
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+	log.Println("Worker stopped")
+}
@@ -0,0 +1,90 @@
+package ledger
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// Account lifecycle errors
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrAccountClosed   = errors.New("account is closed")
+	ErrNonZeroBalance  = errors.New("account balance is not zero")
+)
+
+// SetAccountStatus locks an account, moves it to a new status and records
+// the change for audit. Closing requires a zero balance and soft deletes the
+// account. It must be called inside a database transaction.
+func SetAccountStatus(tx *gorm.DB, accountNumber, to, reason, changedBy string) (*models.Account, error) {
+	accounts, err := LockAccounts(tx, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	account, ok := accounts[accountNumber]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	change, err := account.TransitionTo(to, reason, changedBy)
+	if err != nil {
+		return nil, err
+	}
+	if to == models.AccountClosed && !account.Balance.IsZero() {
+		return nil, ErrNonZeroBalance
+	}
+
+	changes := map[string]interface{}{"status": to}
+	if to == models.AccountClosed {
+		changes["deleted_at"] = time.Now() // Soft delete
+	}
+	if err := UpdateAccount(tx, account, changes); err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&change).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// CloseAccount closes an account. A remaining balance is first swept to
+// sweepTo, converting between currencies if needed; without a sweep account
+// the balance must already be zero. The sweep is allowed from a dormant
+// account, since closing is how its owner gets the money out. It returns the
+// sweep entry, if any. It must be called inside a database transaction.
+func CloseAccount(tx *gorm.DB, rates FXRateProvider, accountNumber, sweepTo, reason, changedBy string) (*models.Account, *models.JournalEntry, error) {
+	numbers := []string{accountNumber}
+	if sweepTo != "" {
+		numbers = append(numbers, sweepTo)
+	}
+	accounts, err := LockAccounts(tx, numbers...) // Both up front, in the same order as transfers
+	if err != nil {
+		return nil, nil, err
+	}
+	account, ok := accounts[accountNumber]
+	if !ok {
+		return nil, nil, ErrAccountNotFound
+	}
+	if !models.CanTransitionAccount(account.Status, models.AccountClosed) {
+		return nil, nil, models.ErrIllegalAccountTransition
+	}
+
+	var sweep *models.JournalEntry
+	if account.Balance.IsPositive() && sweepTo != "" {
+		destination, ok := accounts[sweepTo]
+		if !ok {
+			return nil, nil, ErrDestinationNotFound
+		}
+		if !destination.AllowsCredits() {
+			return nil, nil, ErrDestinationClosed
+		}
+		if sweep, _, err = postTransfer(tx, rates, account, destination, account.Balance, ""); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	closed, err := SetAccountStatus(tx, accountNumber, models.AccountClosed, reason, changedBy)
+	return closed, sweep, err
+}
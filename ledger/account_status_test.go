@@ -0,0 +1,88 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// TestCloseAccount tests that closure requires a zero balance or a sweep,
+// keeps history and is audited
+func TestCloseAccount(t *testing.T) {
+	db := setupTestDB(t)
+	createAccount(t, db, "1111111111", "USD", 5000)
+	createAccount(t, db, "2222222222", "USD", 0)
+	createAccount(t, db, "3333333333", "USD", 0)
+	createAccount(t, db, "4444444444", "USD", 0)
+	createAccount(t, db, "5555555555", "USD", 2000)
+	db.Model(&models.Account{}).Where("account_number = ?", "4444444444").Update("status", models.AccountFrozen)
+	db.Model(&models.Account{}).Where("account_number = ?", "5555555555").Update("status", models.AccountDormant)
+
+	tests := []struct {
+		name        string
+		account     string
+		sweepTo     string
+		expectedErr error
+	}{
+		// ❌ Money left in the account
+		{"Non-zero balance", "1111111111", "", ErrNonZeroBalance},
+		// ❌ Frozen accounts must be unfrozen first
+		{"Frozen account", "4444444444", "", models.ErrIllegalAccountTransition},
+		// ❌ Unknown account
+		{"Unknown account", "0000000000", "", ErrAccountNotFound},
+		// ❌ Unknown sweep account
+		{"Unknown sweep account", "1111111111", "0000000000", ErrDestinationNotFound},
+		// ✅ Sweep the balance, then close
+		{"Sweep and close", "1111111111", "2222222222", nil},
+		// ✅ Dormant accounts can be swept on closure
+		{"Sweep dormant account", "5555555555", "2222222222", nil},
+		// ✅ Already empty
+		{"Zero balance", "3333333333", "", nil},
+		// ❌ Closed accounts are gone
+		{"Already closed", "3333333333", "", ErrAccountNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			err := RunInTransaction(db, func(tx *gorm.DB) error {
+				_, _, err := CloseAccount(tx, nil, tc.account, tc.sweepTo, "test", "user-1")
+				return err
+			})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	// The swept balance moved and the closed account's history is kept
+	if balance, _ := Balance(db, "2222222222"); balance != models.NewMoney(7000, "USD") {
+		t.Errorf("Expected the sweep account to hold 70.00 USD, got %s", balance)
+	}
+	if balance, _ := Balance(db, "1111111111"); !balance.IsZero() {
+		t.Errorf("Expected the closed account to be empty, got %s", balance)
+	}
+	var postings int64
+	db.Model(&models.Posting{}).Where("account_number = ?", "1111111111").Count(&postings)
+	if postings != 2 {
+		t.Errorf("Expected the closed account to keep 2 postings, got %d", postings)
+	}
+
+	// Closed accounts are soft deleted
+	var visible, kept int64
+	db.Model(&models.Account{}).Where("account_number = ?", "1111111111").Count(&visible)
+	db.Unscoped().Model(&models.Account{}).Where("account_number = ? AND status = ?", "1111111111", models.AccountClosed).Count(&kept)
+	if visible != 0 || kept != 1 {
+		t.Errorf("Expected the closed account to be soft deleted, got visible=%d kept=%d", visible, kept)
+	}
+
+	// Only the three successful closures are audited
+	var changes []models.AccountStatusChange
+	db.Order("id").Find(&changes)
+	if len(changes) != 3 || changes[0].AccountNumber != "1111111111" || changes[0].To != models.AccountClosed || changes[0].ChangedBy != "user-1" {
+		t.Errorf("Unexpected audit records: %+v", changes)
+	}
+}
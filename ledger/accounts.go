@@ -0,0 +1,34 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict is returned when an account was changed by someone else
+// after it was read
+var ErrVersionConflict = errors.New("account was modified concurrently")
+
+// UpdateAccount writes changes to an account only if it is still at the
+// version it was read at, and moves it to the next version. On success the
+// account's Version is updated; on a mismatch nothing is written and
+// ErrVersionConflict is returned.
+func UpdateAccount(db *gorm.DB, account *models.Account, changes map[string]interface{}) error {
+	changes["version"] = gorm.Expr("version + 1")
+
+	result := db.Model(&models.Account{}).
+		Where("account_number = ? AND version = ?", account.AccountNumber, account.Version).
+		Updates(changes)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update account %s: %w", account.AccountNumber, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrVersionConflict, account.AccountNumber)
+	}
+
+	account.Version++
+	return nil
+}
@@ -0,0 +1,58 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+)
+
+// TestUpdateAccount tests that account writes are rejected when the account
+// changed since it was read
+func TestUpdateAccount(t *testing.T) {
+	db := setupTestDB(t)
+	createAccount(t, db, "1111111111", "USD", 0)
+
+	var first, second models.Account
+	db.Where("account_number = ?", "1111111111").First(&first)
+	db.Where("account_number = ?", "1111111111").First(&second)
+
+	tests := []struct {
+		name        string
+		account     *models.Account
+		nickname    string
+		expectedErr error
+	}{
+		// ✅ Read at the current version
+		{"Current version", &first, "Rent", nil},
+		// ❌ Read before the first update
+		{"Stale version", &second, "Holidays", ErrVersionConflict},
+		// ✅ The version moved along with the first update
+		{"Next version", &first, "Bills", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			err := UpdateAccount(db, tc.account, map[string]interface{}{"nickname": tc.nickname})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	var stored models.Account
+	db.Where("account_number = ?", "1111111111").First(&stored)
+	if stored.Nickname != "Bills" || stored.Version != 3 || first.Version != 3 {
+		t.Errorf("Expected nickname Bills at version 3, got %q at version %d (in memory %d)", stored.Nickname, stored.Version, first.Version)
+	}
+
+	// Balance changes move the version too, so a stale read cannot overwrite them
+	if err := Post(db, models.NewDepositEntry("1111111111", models.NewMoney(100, "USD"), "")); err != nil {
+		t.Fatalf("Failed to post deposit: %v", err)
+	}
+	if err := UpdateAccount(db, &first, map[string]interface{}{"nickname": "Late"}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected a version conflict after a deposit, got %v", err)
+	}
+}
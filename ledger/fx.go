@@ -0,0 +1,204 @@
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+)
+
+// FXRateProvider quotes exchange rates between currencies.
+type FXRateProvider interface {
+	// Quote returns the rate for converting one unit of from into to.
+	Quote(from, to string) (FXQuote, error)
+}
+
+// FXQuote is a mid-market rate and the spread charged on top of it. Rates are
+// exact fractions so conversions never pass through float64.
+type FXQuote struct {
+	From   string
+	To     string
+	Rate   *big.Rat
+	Spread *big.Rat // Fraction of the rate kept as margin, e.g. 0.005
+}
+
+// EffectiveRate is the rate given to the customer: the mid-market rate less the spread.
+func (q FXQuote) EffectiveRate() *big.Rat {
+	margin := new(big.Rat).Sub(big.NewRat(1, 1), q.Spread)
+	return margin.Mul(margin, q.Rate)
+}
+
+// Convert returns what amount (in the quote's source currency) buys in the
+// target currency, rounded down to the target's minor unit.
+func (q FXQuote) Convert(amount models.Money) (models.Money, error) {
+	if amount.Currency != q.From {
+		return models.Money{}, fmt.Errorf("currency mismatch: %s and %s", amount.Currency, q.From)
+	}
+	return convert(amount, q.To, q.EffectiveRate(), false)
+}
+
+// SourceAmountFor returns how much of the source currency is needed for the
+// target to receive amount, rounded up to the source's minor unit.
+func (q FXQuote) SourceAmountFor(amount models.Money) (models.Money, error) {
+	if amount.Currency != q.To {
+		return models.Money{}, fmt.Errorf("currency mismatch: %s and %s", amount.Currency, q.To)
+	}
+	return convert(amount, q.From, new(big.Rat).Inv(q.EffectiveRate()), true)
+}
+
+// convert multiplies amount by rate and rescales it to the target currency's
+// minor unit. Rounding always favours the bank.
+func convert(amount models.Money, currency string, rate *big.Rat, roundUp bool) (models.Money, error) {
+	fromExp, err := models.CurrencyExponent(amount.Currency)
+	if err != nil {
+		return models.Money{}, err
+	}
+	toExp, err := models.CurrencyExponent(currency)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.MinorUnits), rate)
+	value.Mul(value, new(big.Rat).SetFrac(pow10(toExp), pow10(fromExp)))
+
+	minor := new(big.Int).Quo(value.Num(), value.Denom())
+	if roundUp && !value.IsInt() {
+		minor.Add(minor, big.NewInt(1))
+	}
+	if !minor.IsInt64() {
+		return models.Money{}, fmt.Errorf("converted amount is too large")
+	}
+	if minor.Sign() <= 0 {
+		return models.Money{}, fmt.Errorf("amount %s is too small to convert to %s", amount, currency)
+	}
+	return models.NewMoney(minor.Int64(), currency), nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// FormatRate formats a rate as a decimal string with at most 10 decimal places.
+func FormatRate(rate *big.Rat) string {
+	text := rate.FloatString(10)
+	text = strings.TrimRight(text, "0")
+	return strings.TrimSuffix(text, ".")
+}
+
+// DefaultFXRates is used when no rates file is configured. They are
+// indicative rates for local development only.
+var DefaultFXRates = map[string]string{
+	"USD/EUR": "0.92",
+	"USD/GBP": "0.79",
+	"USD/INR": "83.25",
+	"USD/JPY": "151.50",
+}
+
+// DefaultFXSpread is the spread charged with DefaultFXRates.
+const DefaultFXSpread = "0.005"
+
+// pivotCurrency is used to cross two currencies that have no direct rate
+const pivotCurrency = "USD"
+
+// StaticRates is an FXRateProvider backed by a fixed table of rates keyed
+// "FROM/TO". Inverse rates and crosses through USD are derived as needed.
+type StaticRates struct {
+	spread *big.Rat
+	rates  map[string]*big.Rat
+}
+
+// NewStaticRates parses a table of decimal rates such as {"USD/EUR": "0.92"}.
+func NewStaticRates(spread string, rates map[string]string) (*StaticRates, error) {
+	s := &StaticRates{rates: make(map[string]*big.Rat, len(rates))}
+
+	var ok bool
+	if s.spread, ok = new(big.Rat).SetString(spread); !ok || s.spread.Sign() < 0 || s.spread.Cmp(big.NewRat(1, 1)) >= 0 {
+		return nil, fmt.Errorf("invalid FX spread: %q", spread)
+	}
+
+	for pair, text := range rates {
+		from, to, found := strings.Cut(pair, "/")
+		if !found || !models.IsSupportedCurrency(from) || !models.IsSupportedCurrency(to) {
+			return nil, fmt.Errorf("invalid currency pair: %q", pair)
+		}
+		rate, ok := new(big.Rat).SetString(text)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %q", pair, text)
+		}
+		s.rates[pair] = rate
+	}
+	return s, nil
+}
+
+// LoadStaticRates reads rates from a JSON file of the form
+// {"spread": "0.005", "rates": {"USD/EUR": "0.92"}}.
+func LoadStaticRates(path string) (*StaticRates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Spread string            `json:"spread"`
+		Rates  map[string]string `json:"rates"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid rates file %s: %w", path, err)
+	}
+	return NewStaticRates(file.Spread, file.Rates)
+}
+
+// ErrNoFXRates is returned when no rates file is configured outside development
+var ErrNoFXRates = errors.New("FX_RATES_FILE is unset; configure a rates file or set DEV_MODE=true")
+
+// LoadFXRates builds the rate provider from the environment. FX_RATES_FILE
+// names the rates file; without it, the development rates are only used when
+// DEV_MODE is set, so transfers never settle at made-up rates in production.
+func LoadFXRates() (*StaticRates, error) {
+	if path := os.Getenv("FX_RATES_FILE"); path != "" {
+		return LoadStaticRates(path)
+	}
+	if devMode, _ := strconv.ParseBool(os.Getenv("DEV_MODE")); !devMode {
+		return nil, ErrNoFXRates
+	}
+	return NewStaticRates(DefaultFXSpread, DefaultFXRates)
+}
+
+// Quote implements FXRateProvider.
+func (s *StaticRates) Quote(from, to string) (FXQuote, error) {
+	rate, ok := s.mid(from, to)
+	if !ok {
+		// Cross through the pivot currency
+		first, ok1 := s.mid(from, pivotCurrency)
+		second, ok2 := s.mid(pivotCurrency, to)
+		if !ok1 || !ok2 {
+			return FXQuote{}, fmt.Errorf("no rate from %s to %s", from, to)
+		}
+		rate = new(big.Rat).Mul(first, second)
+	}
+
+	spread := s.spread
+	if from == to {
+		spread = new(big.Rat)
+	}
+	return FXQuote{From: from, To: to, Rate: rate, Spread: spread}, nil
+}
+
+// mid returns the direct or inverse rate between two currencies.
+func (s *StaticRates) mid(from, to string) (*big.Rat, bool) {
+	if from == to {
+		return big.NewRat(1, 1), true
+	}
+	if rate, ok := s.rates[from+"/"+to]; ok {
+		return rate, true
+	}
+	if rate, ok := s.rates[to+"/"+from]; ok {
+		return new(big.Rat).Inv(rate), true
+	}
+	return nil, false
+}
@@ -0,0 +1,115 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+)
+
+// TestStaticRatesConversion tests quoting and converting with the static table
+func TestStaticRatesConversion(t *testing.T) {
+	rates, err := NewStaticRates("0.005", map[string]string{
+		"USD/EUR": "0.92",
+		"USD/JPY": "151.50",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rates: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		amount   models.Money
+		expected models.Money
+		reverse  bool // amount is what the target should receive
+	}{
+		// 💱 Direct rate, rounded down: 100.00 * 0.92 * 0.995 = 91.54
+		{"Direct rate", "USD", "EUR", models.NewMoney(10000, "USD"), models.NewMoney(9154, "EUR"), false},
+		// 💱 Inverse rate: 100.00 / 0.92 * 0.995 = 108.152...
+		{"Inverse rate", "EUR", "USD", models.NewMoney(10000, "EUR"), models.NewMoney(10815, "USD"), false},
+		// 💱 Different exponents: 10.00 USD * 151.50 * 0.995 = 1507.425 JPY
+		{"Zero-decimal currency", "USD", "JPY", models.NewMoney(1000, "USD"), models.NewMoney(1507, "JPY"), false},
+		// 💱 Cross through USD: 1000 JPY / 151.50 * 0.92 * 0.995 = 6.04 EUR
+		{"Cross rate", "JPY", "EUR", models.NewMoney(1000, "JPY"), models.NewMoney(604, "EUR"), false},
+		// 💱 Source amount needed, rounded up: 91.54 EUR costs 100.00 USD
+		{"Source amount for target", "USD", "EUR", models.NewMoney(9154, "EUR"), models.NewMoney(10000, "USD"), true},
+		// 💱 Same currency has no spread
+		{"Same currency", "USD", "USD", models.NewMoney(1234, "USD"), models.NewMoney(1234, "USD"), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			quote, err := rates.Quote(tc.from, tc.to)
+			if err != nil {
+				t.Fatalf("Unexpected quote error: %v", err)
+			}
+
+			var got models.Money
+			if tc.reverse {
+				got, err = quote.SourceAmountFor(tc.amount)
+			} else {
+				got, err = quote.Convert(tc.amount)
+			}
+			if err != nil {
+				t.Fatalf("Unexpected conversion error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestNewStaticRatesRejectsInvalidTable tests validation of the rates table
+func TestNewStaticRatesRejectsInvalidTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		spread string
+		rates  map[string]string
+	}{
+		{"Invalid spread", "1.5", map[string]string{"USD/EUR": "0.92"}},
+		{"Malformed pair", "0.005", map[string]string{"USDEUR": "0.92"}},
+		{"Unsupported currency", "0.005", map[string]string{"USD/XXX": "0.92"}},
+		{"Non-positive rate", "0.005", map[string]string{"USD/EUR": "0"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			if _, err := NewStaticRates(tc.spread, tc.rates); err == nil {
+				t.Error("Expected an error, got none")
+			}
+		})
+	}
+}
+
+// TestLoadFXRates tests that the development rates are refused outside
+// development mode
+func TestLoadFXRates(t *testing.T) {
+	tests := []struct {
+		name        string
+		devMode     string
+		expectedErr error
+	}{
+		// ❌ No rates file in production
+		{"Production", "", ErrNoFXRates},
+		{"Development mode off", "false", ErrNoFXRates},
+		// ✅ Development mode may use the built-in rates
+		{"Development mode", "true", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			t.Setenv("FX_RATES_FILE", "")
+			t.Setenv("DEV_MODE", tc.devMode)
+			if _, err := LoadFXRates(); !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
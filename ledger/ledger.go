@@ -0,0 +1,116 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// Post records a journal entry and refreshes the cached balance of every
+// customer account it touches. It must be called inside a database
+// transaction that already holds row locks on those accounts.
+func Post(tx *gorm.DB, entry *models.JournalEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+
+	// Apply each posting to the accounts.balance projection
+	for _, p := range entry.Postings {
+		if models.IsInternalAccount(p.AccountNumber) {
+			continue
+		}
+
+		// The caller holds the row lock, so the version only needs to move on
+		result := tx.Model(&models.Account{}).
+			Where("account_number = ? AND currency = ?", p.AccountNumber, p.Amount.Currency).
+			Updates(map[string]interface{}{
+				"balance": gorm.Expr("balance + ?", p.SignedAmount().MinorUnits),
+				"version": gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update balance of %s: %w", p.AccountNumber, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("account %s not found or not held in %s", p.AccountNumber, p.Amount.Currency)
+		}
+	}
+
+	return nil
+}
+
+// Balance derives the balance of an account from its postings, ignoring the
+// cached value in accounts.balance.
+func Balance(db *gorm.DB, accountNumber string) (models.Money, error) {
+	var account models.Account
+	if err := db.Unscoped().Where("account_number = ?", accountNumber).First(&account).Error; err != nil {
+		return models.Money{}, err
+	}
+
+	minorUnits, err := sumPostings(db, accountNumber, nil, nil)
+	return models.NewMoney(minorUnits, account.Currency), err
+}
+
+// BalanceAt derives the balance of an account from the postings made before
+// the given time. It starts from the latest balance snapshot at or before
+// that time, so only the postings after the snapshot are replayed.
+func BalanceAt(db *gorm.DB, accountNumber string, at time.Time) (models.Money, error) {
+	var account models.Account
+	if err := db.Unscoped().Where("account_number = ?", accountNumber).First(&account).Error; err != nil {
+		return models.Money{}, err
+	}
+	at = at.UTC()
+
+	var snapshot models.BalanceSnapshot
+	err := db.Where("account_number = ? AND as_of <= ?", accountNumber, at).Order("as_of DESC").First(&snapshot).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Money{}, err
+	}
+
+	var since *time.Time
+	if err == nil {
+		since = &snapshot.AsOf
+	}
+	minorUnits, err := sumPostings(db, accountNumber, since, &at)
+	return models.NewMoney(snapshot.Balance.MinorUnits+minorUnits, account.Currency), err
+}
+
+// sumPostings adds up the signed postings of an account made in
+// [since, before); nil bounds are open.
+func sumPostings(db *gorm.DB, accountNumber string, since, before *time.Time) (int64, error) {
+	query := db.Model(&models.Posting{}).
+		Select("COALESCE(SUM(CASE WHEN direction = ? THEN amount ELSE -amount END), 0)", models.Credit).
+		Where("account_number = ?", accountNumber)
+	if since != nil {
+		query = query.Where("created_at >= ?", since.UTC())
+	}
+	if before != nil {
+		query = query.Where("created_at < ?", before.UTC())
+	}
+
+	var minorUnits int64
+	err := query.Scan(&minorUnits).Error
+	return minorUnits, err
+}
+
+// Rebuild recomputes the cached balance of an account from its postings.
+func Rebuild(db *gorm.DB, accountNumber string) (models.Money, error) {
+	balance, err := Balance(db, accountNumber)
+	if err != nil {
+		return models.Money{}, err
+	}
+
+	err = db.Model(&models.Account{}).
+		Where("account_number = ?", accountNumber).
+		Updates(map[string]interface{}{
+			"balance": balance.MinorUnits,
+			"version": gorm.Expr("version + 1"),
+		}).Error
+	return balance, err
+}
@@ -0,0 +1,83 @@
+package ledger
+
+import (
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTestDB opens an in-memory database with the ledger tables migrated
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Account{}, &models.JournalEntry{}, &models.Posting{}, &models.LedgerTransaction{}, &models.BalanceSnapshot{}, &models.AccountStatusChange{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+// TestPostDerivesBalances tests that cached balances always match the postings
+func TestPostDerivesBalances(t *testing.T) {
+	db := setupTestDB(t)
+
+	for _, number := range []string{"1111111111", "2222222222"} {
+		account := models.Account{AccountNumber: number, OwnerName: "Test", AccountType: "Savings", Currency: "USD"}
+		if err := db.Create(&account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+
+	entries := []*models.JournalEntry{
+		models.NewDepositEntry("1111111111", models.NewMoney(50000, "USD"), ""),
+		models.NewTransferEntry("1111111111", "2222222222", models.NewMoney(20000, "USD"), ""),
+		models.NewWithdrawalEntry("2222222222", models.NewMoney(5000, "USD"), ""),
+	}
+	for _, entry := range entries {
+		if err := Post(db, entry); err != nil {
+			t.Fatalf("Failed to post %s entry: %v", entry.Type, err)
+		}
+	}
+
+	expected := map[string]models.Money{
+		"1111111111": models.NewMoney(30000, "USD"),
+		"2222222222": models.NewMoney(15000, "USD"),
+	}
+	for number, want := range expected {
+		var account models.Account
+		db.Where("account_number = ?", number).First(&account)
+		if account.Balance != want {
+			t.Errorf("Cached balance of %s: expected %s, got %s", number, want, account.Balance)
+		}
+
+		derived, err := Balance(db, number)
+		if err != nil {
+			t.Fatalf("Failed to derive balance: %v", err)
+		}
+		if derived != want {
+			t.Errorf("Derived balance of %s: expected %s, got %s", number, want, derived)
+		}
+	}
+}
+
+// TestPostRejectsUnknownAccount tests that postings to missing accounts fail
+func TestPostRejectsUnknownAccount(t *testing.T) {
+	db := setupTestDB(t)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return Post(tx, models.NewDepositEntry("0000000000", models.NewMoney(1000, "USD"), ""))
+	})
+	if err == nil {
+		t.Fatal("Expected posting to an unknown account to fail")
+	}
+
+	var count int64
+	db.Model(&models.JournalEntry{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected rolled back journal, found %d entries", count)
+	}
+}
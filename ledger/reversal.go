@@ -0,0 +1,156 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// ErrEntryNotFound is returned when the journal entry to reverse does not exist
+var ErrEntryNotFound = errors.New("journal entry not found")
+
+// reversalKey identifies the reversal of an entry; being unique in the
+// journal, it stops an entry from being reversed twice.
+func reversalKey(entryID string) string {
+	return "reversal:" + entryID
+}
+
+// Reverse posts an entry that undoes the given journal entry by swapping the
+// direction of each of its postings. Reversing an already reversed entry
+// returns the existing reversal. A reversal that would overdraw a customer
+// account is refused with ErrInsufficientFunds, and one that touches a
+// closed account with ErrAccountClosed. It must be called inside a
+// database transaction, normally one started by RunInTransaction.
+func Reverse(tx *gorm.DB, entryID, reason string) (*models.JournalEntry, error) {
+	var existing models.JournalEntry
+	err := tx.Preload("Postings").Where("idempotency_key = ?", reversalKey(entryID)).First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var original models.JournalEntry
+	err = tx.Preload("Postings").Where("id = ?", entryID).First(&original).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := reversalKey(entryID)
+	reversal := &models.JournalEntry{
+		Type:           "reversal",
+		Reference:      original.ID,
+		Description:    reason,
+		IdempotencyKey: &key,
+	}
+
+	var customerAccounts []string
+	for _, p := range original.Postings {
+		direction := models.Debit
+		if p.Direction == models.Debit {
+			direction = models.Credit
+		}
+		reversal.Postings = append(reversal.Postings, models.Posting{
+			AccountNumber: p.AccountNumber,
+			Direction:     direction,
+			Amount:        p.Amount,
+		})
+		if !models.IsInternalAccount(p.AccountNumber) {
+			customerAccounts = append(customerAccounts, p.AccountNumber)
+		}
+	}
+
+	// Lock every customer account and make sure none is closed or overdrawn
+	accounts, err := LockAccounts(tx, customerAccounts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, number := range customerAccounts {
+		if _, ok := accounts[number]; !ok {
+			return nil, missingAccountError(tx, number)
+		}
+	}
+	for _, p := range reversal.Postings {
+		account, ok := accounts[p.AccountNumber]
+		if !ok {
+			continue // Internal account
+		}
+		balance, err := account.Balance.Add(p.SignedAmount())
+		if err != nil {
+			return nil, err
+		}
+		if balance.IsNegative() {
+			return nil, fmt.Errorf("%w: reversal would overdraw account %s", ErrInsufficientFunds, p.AccountNumber)
+		}
+		account.Balance = balance
+	}
+
+	if err := Post(tx, reversal); err != nil {
+		return nil, err
+	}
+
+	// Record the reversal as the mirror of the original transaction
+	record, err := reversalRecord(tx, &original)
+	if err != nil {
+		return nil, err
+	}
+	if err := Record(tx, reversal, record); err != nil {
+		return nil, err
+	}
+	err = tx.Model(&models.LedgerTransaction{}).
+		Where("journal_entry_id = ?", entryID).
+		Update("status", models.StatusReversed).Error
+	if err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// missingAccountError explains why a customer account could not be locked:
+// closed accounts are soft deleted and so hidden from LockAccounts.
+func missingAccountError(tx *gorm.DB, accountNumber string) error {
+	var account models.Account
+	err := tx.Unscoped().Where("account_number = ?", accountNumber).First(&account).Error
+	switch {
+	case err == nil:
+		return fmt.Errorf("%w: %s", ErrAccountClosed, accountNumber)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%w: %s", ErrAccountNotFound, accountNumber)
+	default:
+		return err
+	}
+}
+
+// reversalRecord describes the reversal of an entry as a ledger transaction
+// moving the same amount back. Entries posted before transactions were
+// recorded fall back to their first customer posting.
+func reversalRecord(tx *gorm.DB, original *models.JournalEntry) (*models.LedgerTransaction, error) {
+	record := &models.LedgerTransaction{Reference: original.ID}
+
+	var recorded models.LedgerTransaction
+	err := tx.Where("journal_entry_id = ?", original.ID).First(&recorded).Error
+	switch {
+	case err == nil:
+		record.AccountNumber = recorded.AccountNumber
+		record.SourceAccount = recorded.DestinationAccount
+		record.DestinationAccount = recorded.SourceAccount
+		record.Amount = recorded.Amount
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		for _, p := range original.Postings {
+			if !models.IsInternalAccount(p.AccountNumber) {
+				record.AccountNumber = p.AccountNumber
+				record.Amount = p.Amount
+				break
+			}
+		}
+	default:
+		return nil, err
+	}
+	return record, nil
+}
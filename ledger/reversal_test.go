@@ -0,0 +1,123 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// TestReverseRestoresBalances tests that a reversal undoes a transfer exactly once
+func TestReverseRestoresBalances(t *testing.T) {
+	db := setupTestDB(t)
+	createAccount(t, db, "1111111111", "USD", 10000)
+	createAccount(t, db, "2222222222", "USD", 0)
+
+	var transfer *models.JournalEntry
+	err := RunInTransaction(db, func(tx *gorm.DB) error {
+		var err error
+		transfer, _, err = Transfer(tx, nil, "1111111111", "2222222222", models.NewMoney(4000, "USD"), "")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to transfer: %v", err)
+	}
+
+	// Reversing twice posts a single reversal
+	var first, second *models.JournalEntry
+	for _, reversal := range []**models.JournalEntry{&first, &second} {
+		err := RunInTransaction(db, func(tx *gorm.DB) error {
+			var err error
+			*reversal, err = Reverse(tx, transfer.ID, "sent to the wrong account")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Failed to reverse: %v", err)
+		}
+	}
+	if first.ID != second.ID {
+		t.Errorf("Expected the second reversal to return the first, got %s and %s", first.ID, second.ID)
+	}
+
+	for number, want := range map[string]models.Money{
+		"1111111111": models.NewMoney(10000, "USD"),
+		"2222222222": models.NewMoney(0, "USD"),
+	} {
+		balance, _ := Balance(db, number)
+		if balance != want {
+			t.Errorf("Balance of %s: expected %s, got %s", number, want, balance)
+		}
+	}
+}
+
+// TestReverseRefusesOverdraft tests that a reversal cannot take back money already spent
+func TestReverseRefusesOverdraft(t *testing.T) {
+	db := setupTestDB(t)
+	createAccount(t, db, "1111111111", "USD", 0)
+
+	deposit := models.NewDepositEntry("1111111111", models.NewMoney(5000, "USD"), "")
+	if err := Post(db, deposit); err != nil {
+		t.Fatalf("Failed to deposit: %v", err)
+	}
+	if err := Post(db, models.NewWithdrawalEntry("1111111111", models.NewMoney(3000, "USD"), "")); err != nil {
+		t.Fatalf("Failed to withdraw: %v", err)
+	}
+
+	err := RunInTransaction(db, func(tx *gorm.DB) error {
+		_, err := Reverse(tx, deposit.ID, "chargeback")
+		return err
+	})
+	if !errors.Is(err, ErrInsufficientFunds) {
+		t.Fatalf("Expected insufficient funds, got %v", err)
+	}
+
+	if err := RunInTransaction(db, func(tx *gorm.DB) error {
+		_, err := Reverse(tx, "missing", "typo")
+		return err
+	}); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("Expected entry not found, got %v", err)
+	}
+}
+
+// TestReverseRefusesClosedAccount tests that a reversal cannot move money in
+// or out of an account that has since been closed
+func TestReverseRefusesClosedAccount(t *testing.T) {
+	db := setupTestDB(t)
+	createAccount(t, db, "1111111111", "USD", 10000)
+	createAccount(t, db, "2222222222", "USD", 0)
+	createAccount(t, db, "3333333333", "USD", 0)
+
+	var transfer *models.JournalEntry
+	err := RunInTransaction(db, func(tx *gorm.DB) error {
+		var err error
+		transfer, _, err = Transfer(tx, nil, "1111111111", "2222222222", models.NewMoney(4000, "USD"), "")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to transfer: %v", err)
+	}
+
+	// Close the destination, sweeping its balance elsewhere
+	err = RunInTransaction(db, func(tx *gorm.DB) error {
+		_, _, err := CloseAccount(tx, nil, "2222222222", "3333333333", "customer request", "admin")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to close account: %v", err)
+	}
+
+	err = RunInTransaction(db, func(tx *gorm.DB) error {
+		_, err := Reverse(tx, transfer.ID, "sent to the wrong account")
+		return err
+	})
+	if !errors.Is(err, ErrAccountClosed) {
+		t.Fatalf("Expected account closed, got %v", err)
+	}
+
+	// Nothing moved
+	balance, _ := Balance(db, "1111111111")
+	if want := models.NewMoney(6000, "USD"); balance != want {
+		t.Errorf("Balance of 1111111111: expected %s, got %s", want, balance)
+	}
+}
@@ -0,0 +1,50 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SnapshotLag is how long after a snapshot boundary the snapshot is taken.
+// Postings are timestamped before they commit, so a posting stamped just
+// before the boundary may still be in flight when the boundary passes.
+const SnapshotLag = 5 * time.Minute
+
+// SnapshotBoundary returns the latest boundary, a multiple of interval in
+// UTC, that is safe to snapshot at the given time.
+func SnapshotBoundary(now time.Time, interval time.Duration) time.Time {
+	return now.UTC().Add(-SnapshotLag).Truncate(interval)
+}
+
+// TakeSnapshots records the balance as of the given time of every account
+// opened before it. Accounts that already have a snapshot at that time are
+// skipped, so running it twice or from several processes is harmless. It
+// returns the number of snapshots written.
+func TakeSnapshots(db *gorm.DB, asOf time.Time) (int, error) {
+	asOf = asOf.UTC()
+	written := 0
+
+	var accounts []models.Account
+	err := db.Where("created_at < ?", asOf).
+		Where("NOT EXISTS (SELECT 1 FROM balance_snapshots WHERE balance_snapshots.account_number = accounts.account_number AND balance_snapshots.as_of = ?)", asOf).
+		FindInBatches(&accounts, 100, func(batch *gorm.DB, _ int) error {
+			for _, account := range accounts {
+				balance, err := BalanceAt(db, account.AccountNumber, asOf)
+				if err != nil {
+					return err
+				}
+
+				snapshot := models.BalanceSnapshot{AccountNumber: account.AccountNumber, AsOf: asOf, Balance: balance}
+				result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&snapshot)
+				if result.Error != nil {
+					return result.Error
+				}
+				written += int(result.RowsAffected)
+			}
+			return nil
+		}).Error
+	return written, err
+}
@@ -0,0 +1,97 @@
+package ledger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+)
+
+// TestBalanceAt tests historical balances with and without a snapshot
+func TestBalanceAt(t *testing.T) {
+	db := setupTestDB(t)
+	account := models.Account{AccountNumber: "1111111111", OwnerName: "Test", AccountType: "Savings", Currency: "USD", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+
+	// Deposits of 10.00 on Jan 10, 20.00 on Jan 20 and 30.00 on Feb 10
+	for _, deposit := range []struct {
+		day    time.Time
+		amount int64
+	}{
+		{time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC), 1000},
+		{time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC), 2000},
+		{time.Date(2024, 2, 10, 12, 0, 0, 0, time.UTC), 3000},
+	} {
+		entry := models.NewDepositEntry("1111111111", models.NewMoney(deposit.amount, "USD"), "")
+		for i := range entry.Postings {
+			entry.Postings[i].CreatedAt = deposit.day
+		}
+		if err := Post(db, entry); err != nil {
+			t.Fatalf("Failed to post deposit: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		expected int64
+	}{
+		{"Before any posting", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0},
+		{"Between postings", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), 1000},
+		{"At the snapshot", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 3000},
+		{"After the snapshot", time.Date(2024, 2, 11, 0, 0, 0, 0, time.UTC), 6000},
+	}
+
+	check := func(stage string) {
+		for _, tc := range tests {
+			t.Run(stage+" "+tc.name, func(t *testing.T) {
+				fmt.Println("Running test:", stage, tc.name) // Print log
+				balance, err := BalanceAt(db, "1111111111", tc.at)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if balance != models.NewMoney(tc.expected, "USD") {
+					t.Errorf("Expected %d, got %s", tc.expected, balance)
+				}
+			})
+		}
+	}
+
+	check("Without snapshot:")
+
+	boundary := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	for _, expected := range []int{1, 0} { // The second run finds the snapshot already taken
+		written, err := TakeSnapshots(db, boundary)
+		if err != nil {
+			t.Fatalf("Failed to take snapshots: %v", err)
+		}
+		if written != expected {
+			t.Errorf("Expected %d snapshots written, got %d", expected, written)
+		}
+	}
+
+	var snapshot models.BalanceSnapshot
+	if err := db.Where("account_number = ? AND as_of = ?", "1111111111", boundary).First(&snapshot).Error; err != nil {
+		t.Fatalf("Snapshot not found: %v", err)
+	}
+	if snapshot.Balance != models.NewMoney(3000, "USD") {
+		t.Errorf("Expected snapshot of 30.00 USD, got %s", snapshot.Balance)
+	}
+
+	check("With snapshot:")
+}
+
+// TestSnapshotBoundary tests that a boundary is only snapshotted after the lag
+func TestSnapshotBoundary(t *testing.T) {
+	midnight := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	if got := SnapshotBoundary(midnight.Add(SnapshotLag-time.Second), 24*time.Hour); !got.Equal(midnight.Add(-24 * time.Hour)) {
+		t.Errorf("Expected the previous boundary within the lag, got %s", got)
+	}
+	if got := SnapshotBoundary(midnight.Add(SnapshotLag), 24*time.Hour); !got.Equal(midnight) {
+		t.Errorf("Expected %s after the lag, got %s", midnight, got)
+	}
+}
@@ -0,0 +1,83 @@
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// Record writes the ledger transaction for a posted journal entry. It must be
+// called in the same database transaction as Post.
+func Record(tx *gorm.DB, entry *models.JournalEntry, txn *models.LedgerTransaction) error {
+	txn.JournalEntryID = entry.ID
+	if txn.Type == "" {
+		txn.Type = entry.Type
+	}
+	if err := tx.Create(txn).Error; err != nil {
+		return fmt.Errorf("failed to record transaction: %w", err)
+	}
+	return nil
+}
+
+// ListTransactions returns one page of ledger transactions matching the
+// query, plus the cursor of the next page ("" on the last page).
+func ListTransactions(db *gorm.DB, q models.TransactionQuery) ([]models.LedgerTransaction, string, error) {
+	query := db.Model(&models.LedgerTransaction{})
+
+	if len(q.AccountNumbers) > 0 {
+		accounts := q.Accounts()
+		if len(accounts) == 0 {
+			return []models.LedgerTransaction{}, "", nil
+		}
+		query = query.Where("account_number IN ? OR source_account IN ? OR destination_account IN ?",
+			accounts, accounts, accounts)
+	}
+	if q.Counterparty != "" {
+		query = query.Where("source_account = ? OR destination_account = ?", q.Counterparty, q.Counterparty)
+	}
+	if q.From != nil {
+		query = query.Where("created_at >= ?", q.From.UTC())
+	}
+	if q.To != nil {
+		query = query.Where("created_at < ?", q.To.UTC())
+	}
+	if q.Type != "" {
+		query = query.Where("type = ?", q.Type)
+	}
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if q.Currency != "" {
+		query = query.Where("currency = ?", q.Currency)
+	}
+	if q.MinAmount != nil {
+		query = query.Where("amount >= ?", q.MinAmount.MinorUnits)
+	}
+	if q.MaxAmount != nil {
+		query = query.Where("amount <= ?", q.MaxAmount.MinorUnits)
+	}
+
+	// Keyset pagination on (created_at, id)
+	op, order := "<", "created_at DESC, id DESC"
+	if q.Ascending {
+		op, order = ">", "created_at ASC, id ASC"
+	}
+	if q.After != nil {
+		after := q.After.CreatedAt.UTC()
+		query = query.Where(fmt.Sprintf("created_at %s ? OR (created_at = ? AND id %s ?)", op, op),
+			after, after, q.After.ID)
+	}
+
+	var transactions []models.LedgerTransaction
+	if err := query.Order(order).Limit(q.Limit + 1).Find(&transactions).Error; err != nil {
+		return nil, "", err
+	}
+
+	if len(transactions) <= q.Limit {
+		return transactions, "", nil
+	}
+	transactions = transactions[:q.Limit]
+	last := transactions[q.Limit-1]
+	return transactions, models.PageCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode(), nil
+}
@@ -0,0 +1,89 @@
+package ledger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// TestListTransactions tests filtering and paging recorded transactions
+func TestListTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	createAccount(t, db, "1111111111", "USD", 100000)
+	createAccount(t, db, "2222222222", "USD", 0)
+	createAccount(t, db, "3333333333", "USD", 0)
+
+	// Five transfers of 10.00, 20.00, ... alternating between two recipients
+	var entries []*models.JournalEntry
+	for i := 1; i <= 5; i++ {
+		destination := "2222222222"
+		if i%2 == 0 {
+			destination = "3333333333"
+		}
+		err := RunInTransaction(db, func(tx *gorm.DB) error {
+			entry, _, err := Transfer(tx, nil, "1111111111", destination, models.NewMoney(int64(i)*1000, "USD"), "")
+			entries = append(entries, entry)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Failed to transfer: %v", err)
+		}
+	}
+	err := RunInTransaction(db, func(tx *gorm.DB) error {
+		_, err := Reverse(tx, entries[0].ID, "duplicate")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to reverse: %v", err)
+	}
+
+	min, max := models.NewMoney(2000, "USD"), models.NewMoney(4000, "USD")
+	tests := []struct {
+		name     string
+		query    models.TransactionQuery
+		expected []int64 // Amounts in minor units, in page order
+	}{
+		{"Newest first", models.TransactionQuery{AccountNumbers: []string{"1111111111"}, Type: "transfer"}, []int64{5000, 4000, 3000, 2000, 1000}},
+		{"Oldest first", models.TransactionQuery{AccountNumbers: []string{"1111111111"}, Type: "transfer", Ascending: true}, []int64{1000, 2000, 3000, 4000, 5000}},
+		{"One recipient", models.TransactionQuery{AccountNumbers: []string{"3333333333"}}, []int64{4000, 2000}},
+		{"Counterparty", models.TransactionQuery{AccountNumbers: []string{"1111111111"}, Counterparty: "3333333333"}, []int64{4000, 2000}},
+		{"Amount range", models.TransactionQuery{AccountNumbers: []string{"1111111111"}, Currency: "USD", MinAmount: &min, MaxAmount: &max}, []int64{4000, 3000, 2000}},
+		{"Reversed", models.TransactionQuery{AccountNumbers: []string{"1111111111"}, Status: models.StatusReversed}, []int64{1000}},
+		{"Reversal", models.TransactionQuery{AccountNumbers: []string{"1111111111"}, Type: "reversal"}, []int64{1000}},
+		{"Unknown account", models.TransactionQuery{AccountNumbers: []string{"9999999999"}}, nil},
+		{"Blank account", models.TransactionQuery{AccountNumbers: []string{""}}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+
+			// Walk every page two at a time
+			var amounts []int64
+			query := tc.query
+			query.Limit = 2
+			for page := 0; page < 10; page++ {
+				transactions, next, err := ListTransactions(db, query)
+				if err != nil {
+					t.Fatalf("Failed to list transactions: %v", err)
+				}
+				for _, txn := range transactions {
+					amounts = append(amounts, txn.Amount.MinorUnits)
+				}
+				if next == "" {
+					break
+				}
+				query.After, err = models.DecodePageCursor(next)
+				if err != nil {
+					t.Fatalf("Failed to decode cursor: %v", err)
+				}
+			}
+
+			if fmt.Sprint(amounts) != fmt.Sprint(tc.expected) {
+				t.Errorf("Expected amounts %v, got %v", tc.expected, amounts)
+			}
+		})
+	}
+}
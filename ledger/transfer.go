@@ -0,0 +1,196 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Transfer errors that are the caller's fault rather than the database's
+var (
+	ErrSourceNotFound      = errors.New("source account not found")
+	ErrDestinationNotFound = errors.New("destination account not found")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrAccountFrozen       = errors.New("source account is frozen")
+	ErrAccountDormant      = errors.New("source account is dormant")
+	ErrDestinationClosed   = errors.New("destination account is closed")
+	ErrCurrencyMismatch    = errors.New("transfer currency must match the source or destination account currency")
+	ErrConversion          = errors.New("transfer amount cannot be converted")
+)
+
+// Postgres error codes for transactions that lost a race and can be re-run
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// MaxTransactionAttempts bounds how often RunInTransaction runs a transaction
+const MaxTransactionAttempts = 5
+
+// RunInTransaction runs fn in a database transaction and re-runs it from the
+// start when Postgres aborts it with a serialization failure or deadlock. fn
+// must not keep state between attempts.
+func RunInTransaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	var err error
+	for attempt := 1; attempt <= MaxTransactionAttempts; attempt++ {
+		err = db.Transaction(fn)
+		if !IsRetryable(err) {
+			return err
+		}
+
+		// Back off with jitter so the competing transactions do not collide again
+		backoff := time.Duration(attempt*attempt) * 10 * time.Millisecond
+		time.Sleep(backoff + time.Duration(rand.Int63n(int64(backoff))))
+	}
+	return err
+}
+
+// IsRetryable reports whether err is a serialization failure or deadlock.
+func IsRetryable(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+	code := sqlErr.SQLState()
+	return code == serializationFailure || code == deadlockDetected
+}
+
+// LockAccounts locks the given accounts with SELECT ... FOR UPDATE, one at a
+// time in account number order. Every caller taking locks in the same order
+// means two transactions can never wait on each other in a cycle. Accounts
+// that do not exist are absent from the result.
+func LockAccounts(tx *gorm.DB, accountNumbers ...string) (map[string]*models.Account, error) {
+	sorted := append([]string(nil), accountNumbers...)
+	sort.Strings(sorted)
+
+	accounts := make(map[string]*models.Account, len(sorted))
+	for _, number := range sorted {
+		if _, locked := accounts[number]; locked {
+			continue
+		}
+
+		var account models.Account
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("account_number = ?", number).
+			First(&account).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		accounts[number] = &account
+	}
+	return accounts, nil
+}
+
+// debitBlocked explains why money may not leave an account
+func debitBlocked(account *models.Account) error {
+	if account.Status == models.AccountDormant {
+		return ErrAccountDormant
+	}
+	return ErrAccountFrozen
+}
+
+// Transfer locks both accounts, checks the transfer can be made and posts it.
+// The amount may be given in either account's currency; between accounts in
+// different currencies it is converted at the rate quoted by rates, and the
+// conversion is returned so it can be recorded. It must be called inside a
+// database transaction, normally one started by RunInTransaction.
+func Transfer(tx *gorm.DB, rates FXRateProvider, source, destination string, amount models.Money, idempotencyKey string) (*models.JournalEntry, *models.FXConversion, error) {
+	accounts, err := LockAccounts(tx, source, destination)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sourceAccount, ok := accounts[source]
+	if !ok {
+		return nil, nil, ErrSourceNotFound
+	}
+	destinationAccount, ok := accounts[destination]
+	if !ok {
+		return nil, nil, ErrDestinationNotFound
+	}
+	if !sourceAccount.AllowsDebits() {
+		return nil, nil, debitBlocked(sourceAccount)
+	}
+	if !destinationAccount.AllowsCredits() {
+		return nil, nil, ErrDestinationClosed
+	}
+	return postTransfer(tx, rates, sourceAccount, destinationAccount, amount, idempotencyKey)
+}
+
+// postTransfer converts and posts a transfer between two locked accounts
+// whose statuses have already been checked
+func postTransfer(tx *gorm.DB, rates FXRateProvider, sourceAccount, destinationAccount *models.Account, amount models.Money, idempotencyKey string) (*models.JournalEntry, *models.FXConversion, error) {
+	source, destination := sourceAccount.AccountNumber, destinationAccount.AccountNumber
+	if amount.Currency != sourceAccount.Currency && amount.Currency != destinationAccount.Currency {
+		return nil, nil, ErrCurrencyMismatch
+	}
+
+	// Work out what leaves the source and what reaches the destination
+	debit, credit := amount, amount
+	var conversion *models.FXConversion
+	if sourceAccount.Currency != destinationAccount.Currency {
+		if rates == nil {
+			return nil, nil, ErrConversion
+		}
+		quote, err := rates.Quote(sourceAccount.Currency, destinationAccount.Currency)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrConversion, err)
+		}
+
+		if amount.Currency == sourceAccount.Currency {
+			credit, err = quote.Convert(amount)
+		} else {
+			debit, err = quote.SourceAmountFor(amount)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrConversion, err)
+		}
+
+		conversion = &models.FXConversion{
+			Rate:              FormatRate(quote.Rate),
+			Spread:            FormatRate(quote.Spread),
+			SourceAmount:      debit,
+			DestinationAmount: credit,
+		}
+	}
+
+	// Check if the source account has enough balance
+	cmp, err := sourceAccount.Balance.Cmp(debit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cmp < 0 {
+		return nil, nil, ErrInsufficientFunds
+	}
+
+	// Record the transfer in the journal; balances are updated from its postings
+	entry := models.NewTransferEntry(source, destination, amount, "")
+	if conversion != nil {
+		entry = models.NewFXTransferEntry(source, destination, debit, credit, "")
+	}
+	if idempotencyKey != "" {
+		entry.IdempotencyKey = &idempotencyKey
+	}
+	if err := Post(tx, entry); err != nil {
+		return nil, nil, err
+	}
+	err = Record(tx, entry, &models.LedgerTransaction{
+		SourceAccount:      source,
+		DestinationAccount: destination,
+		Amount:             amount,
+		FX:                 conversion,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry, conversion, nil
+}
@@ -0,0 +1,207 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// createAccount opens an account and funds it through the journal. An empty
+// number is generated and returned.
+func createAccount(t *testing.T, db *gorm.DB, number, currency string, minorUnits int64) string {
+	account := models.Account{UserID: "test-user", AccountNumber: number, OwnerName: "Test", AccountType: "Savings", Currency: currency}
+	if err := db.Create(&account).Error; err != nil {
+		t.Fatalf("Failed to create account: %v", err)
+	}
+	if minorUnits > 0 {
+		if err := Post(db, models.NewDepositEntry(account.AccountNumber, models.NewMoney(minorUnits, currency), "")); err != nil {
+			t.Fatalf("Failed to fund account: %v", err)
+		}
+	}
+	return account.AccountNumber
+}
+
+// TestTransfer tests the checks made before a transfer is posted
+func TestTransfer(t *testing.T) {
+	db := setupTestDB(t)
+	createAccount(t, db, "1111111111", "USD", 10000)
+	createAccount(t, db, "2222222222", "USD", 0)
+	createAccount(t, db, "3333333333", "EUR", 0)
+	createAccount(t, db, "4444444444", "JPY", 0)
+	createAccount(t, db, "5555555555", "USD", 1000)
+	db.Model(&models.Account{}).Where("account_number = ?", "5555555555").Update("status", models.AccountFrozen)
+	createAccount(t, db, "6666666666", "USD", 1000)
+	db.Model(&models.Account{}).Where("account_number = ?", "6666666666").Update("status", models.AccountDormant)
+
+	rates, err := NewStaticRates("0.01", map[string]string{"USD/EUR": "0.9"})
+	if err != nil {
+		t.Fatalf("Failed to create rates: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		source      string
+		destination string
+		amount      models.Money
+		expectedErr error
+	}{
+		// ✅ Valid transfer
+		{"Valid transfer", "1111111111", "2222222222", models.NewMoney(2500, "USD"), nil},
+		// ✅ Cross-currency transfers, in either account's currency
+		{"Converted from source currency", "1111111111", "3333333333", models.NewMoney(1000, "USD"), nil},
+		{"Converted to destination currency", "1111111111", "3333333333", models.NewMoney(891, "EUR"), nil},
+		// ❌ Unknown accounts
+		{"Unknown source", "0000000000", "2222222222", models.NewMoney(100, "USD"), ErrSourceNotFound},
+		{"Unknown destination", "1111111111", "0000000000", models.NewMoney(100, "USD"), ErrDestinationNotFound},
+		// ❌ Not enough money
+		{"Insufficient funds", "1111111111", "2222222222", models.NewMoney(1000000, "USD"), ErrInsufficientFunds},
+		// ❌ Frozen and dormant accounts cannot send money, but can receive it
+		{"Frozen source", "5555555555", "2222222222", models.NewMoney(100, "USD"), ErrAccountFrozen},
+		{"Dormant source", "6666666666", "2222222222", models.NewMoney(100, "USD"), ErrAccountDormant},
+		{"Dormant destination", "1111111111", "6666666666", models.NewMoney(100, "USD"), nil},
+		// ❌ Currency matches neither account
+		{"Currency mismatch", "1111111111", "2222222222", models.NewMoney(100, "EUR"), ErrCurrencyMismatch},
+		// ❌ No rate between the account currencies
+		{"No exchange rate", "1111111111", "4444444444", models.NewMoney(100, "USD"), ErrConversion},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			err := RunInTransaction(db, func(tx *gorm.DB) error {
+				_, _, err := Transfer(tx, rates, tc.source, tc.destination, tc.amount, "")
+				return err
+			})
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+
+	// Only the valid transfers are posted: 1000 USD buys 891 EUR at 0.9 less 1%
+	expected := map[string]models.Money{
+		"1111111111": models.NewMoney(5400, "USD"),
+		"2222222222": models.NewMoney(2500, "USD"),
+		"3333333333": models.NewMoney(1782, "EUR"),
+		"4444444444": models.NewMoney(0, "JPY"),
+		"6666666666": models.NewMoney(1100, "USD"),
+	}
+	for number, want := range expected {
+		balance, _ := Balance(db, number)
+		if balance != want {
+			t.Errorf("Balance of %s: expected %s, got %s", number, want, balance)
+		}
+	}
+}
+
+// sqlStateError mimics a driver error carrying a SQLSTATE code
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+// TestIsRetryable tests which database errors cause a transaction to be re-run
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"Serialization failure", sqlStateError("40001"), true},
+		{"Deadlock", fmt.Errorf("post transfer: %w", sqlStateError("40P01")), true},
+		{"Unique violation", sqlStateError("23505"), false},
+		{"Business error", ErrInsufficientFunds, false},
+		{"No error", nil, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			if got := IsRetryable(tc.err); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestConcurrentOppositeTransfers hammers two accounts with transfers in both
+// directions and checks that no money is created or lost. Row locks need a
+// real PostgreSQL server, so the test only runs when TEST_POSTGRES_DSN is set.
+func TestConcurrentOppositeTransfers(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Account{}, &models.JournalEntry{}, &models.Posting{}, &models.LedgerTransaction{}, &models.BalanceSnapshot{}, &models.AccountStatusChange{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	// Fresh accounts so the test can run against a shared database
+	a := createAccount(t, db, "", "USD", 100000)
+	b := createAccount(t, db, "", "USD", 100000)
+
+	const workers, transfersPerWorker = 16, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*transfersPerWorker)
+
+	for i := 0; i < workers; i++ {
+		source, destination := a, b
+		if i%2 == 1 {
+			source, destination = b, a // Opposite direction
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < transfersPerWorker; j++ {
+				err := RunInTransaction(db, func(tx *gorm.DB) error {
+					_, _, err := Transfer(tx, nil, source, destination, models.NewMoney(int64(100+j), "USD"), "")
+					return err
+				})
+				if err != nil && !errors.Is(err, ErrInsufficientFunds) {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Transfer failed: %v", err)
+	}
+
+	total := int64(0)
+	for _, number := range []string{a, b} {
+		var account models.Account
+		db.Where("account_number = ?", number).First(&account)
+
+		derived, err := Balance(db, number)
+		if err != nil {
+			t.Fatalf("Failed to derive balance: %v", err)
+		}
+		if account.Balance != derived {
+			t.Errorf("Cached balance of %s (%s) does not match its postings (%s)", number, account.Balance, derived)
+		}
+		if account.Balance.IsNegative() {
+			t.Errorf("Account %s overdrawn: %s", number, account.Balance)
+		}
+		total += account.Balance.MinorUnits
+	}
+
+	if total != 200000 {
+		t.Errorf("Total balance not conserved: expected 200000, got %d", total)
+	}
+}
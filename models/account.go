@@ -22,10 +22,17 @@ type Account struct {
 	OwnerName     string    `gorm:"type:text;not null" json:"owner_name"`
 	AccountNumber string    `gorm:"type:text;unique;not null" json:"account_number"`
 	AccountType   string    `gorm:"type:text;not null" json:"account_type"`
-	Balance       float64   `gorm:"not null;default:0" json:"balance"`
+	Nickname      string    `gorm:"type:text;not null;default:''" json:"nickname,omitempty"`
+	Balance       Money     `gorm:"not null;default:0" json:"balance"`
 	Currency      string    `gorm:"type:text;not null" json:"currency"`
+	Status        string    `gorm:"type:text;not null;default:'active'" json:"status"`
+	Version       int64     `gorm:"not null;default:1" json:"version"` // Bumped by every write, deposits and status changes included
 	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// DeletedAt is set when the account is closed. Closed accounts drop out
+	// of normal queries but keep their row, so history stays intact.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // AllowedAccountTypes defines the valid types for an account.
@@ -38,6 +45,10 @@ var AllowedAccountTypes = map[string]bool{
 // BeforeCreate runs before inserting a new record.
 func (a *Account) BeforeCreate(tx *gorm.DB) (err error) {
 	a.ID = uuid.New().String()
+	a.Version = 1
+	if a.Status == "" {
+		a.Status = AccountActive
+	}
 
 	// Generate unique account number if not set
 	if a.AccountNumber == "" {
@@ -46,6 +57,17 @@ func (a *Account) BeforeCreate(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// ETag returns the entity tag of the account's current version.
+func (a *Account) ETag() string {
+	return fmt.Sprintf(`"%d"`, a.Version)
+}
+
+// AfterFind attaches the account currency to the balance after loading.
+func (a *Account) AfterFind(tx *gorm.DB) (err error) {
+	a.Balance.Currency = a.Currency
+	return nil
+}
+
 // Validate ensures that the account has valid data.
 func (a *Account) Validate() error {
 	// Ensure Owner Name is not empty
@@ -67,16 +89,25 @@ func (a *Account) Validate() error {
 		return fmt.Errorf("invalid account type: must be Savings, Checking, or Business (got '%s')", a.AccountType)
 	}
 
-	// Ensure Balance is not negative
-	if a.Balance < 0 {
-		return errors.New("balance cannot be negative")
-	}
-
 	// Validate currency format (ISO 4217, e.g., USD, EUR)
 	currencyRegex := regexp.MustCompile(`^[A-Z]{3}$`)
 	if !currencyRegex.MatchString(a.Currency) {
 		return errors.New("invalid currency format; must be a 3-letter ISO code (e.g., USD, EUR)")
 	}
+	if !IsSupportedCurrency(a.Currency) {
+		return fmt.Errorf("unsupported currency: %s", a.Currency)
+	}
+
+	// Ensure Balance is in the account currency and not negative
+	if a.Balance.Currency == "" {
+		a.Balance.Currency = a.Currency
+	}
+	if a.Balance.Currency != a.Currency {
+		return errors.New("balance currency must match account currency")
+	}
+	if a.Balance.IsNegative() {
+		return errors.New("balance cannot be negative")
+	}
 
 	return nil
 }
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxNicknameLength bounds the customer-chosen account nickname
+const MaxNicknameLength = 50
+
+// AccountPatch is a JSON Merge Patch (RFC 7396) of the fields a customer may
+// change on an account. A nil field is left unchanged. Everything else on
+// the account is owned by the ledger or by staff.
+type AccountPatch struct {
+	OwnerName   *string
+	AccountType *string
+	Nickname    *string // An empty nickname removes it
+}
+
+// ParseAccountPatch reads a merge patch document. Members other than
+// owner_name, account_type and nickname are rejected rather than ignored, so
+// a client trying to set e.g. balance learns that it cannot. A null nickname
+// removes it; the other fields are required and cannot be null.
+func ParseAccountPatch(body []byte) (AccountPatch, error) {
+	var patch AccountPatch
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return patch, errors.New("patch must be a JSON object")
+	}
+
+	for name, raw := range members {
+		var target **string
+		switch name {
+		case "owner_name":
+			target = &patch.OwnerName
+		case "account_type":
+			target = &patch.AccountType
+		case "nickname":
+			target = &patch.Nickname
+		default:
+			return patch, fmt.Errorf("field %q cannot be updated", name)
+		}
+
+		if string(raw) == "null" {
+			if name != "nickname" {
+				return patch, fmt.Errorf("field %q cannot be removed", name)
+			}
+			empty := ""
+			*target = &empty
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return patch, fmt.Errorf("field %q must be a string", name)
+		}
+		*target = &value
+	}
+
+	return patch, nil
+}
+
+// Apply merges the patch into the account and validates the result.
+func (p AccountPatch) Apply(a *Account) error {
+	if p.OwnerName != nil {
+		a.OwnerName = strings.TrimSpace(*p.OwnerName)
+	}
+	if p.AccountType != nil {
+		a.AccountType = *p.AccountType
+	}
+	if p.Nickname != nil {
+		a.Nickname = strings.TrimSpace(*p.Nickname)
+	}
+
+	if utf8.RuneCountInString(a.Nickname) > MaxNicknameLength {
+		return fmt.Errorf("nickname must be at most %d characters", MaxNicknameLength)
+	}
+	return a.Validate()
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestAccountPatch tests merge patch parsing, the field whitelist and validation
+func TestAccountPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expectError bool
+		expected    Account // Fields after the patch is applied
+	}{
+		// ✅ Valid patches
+		{"Empty patch", `{}`, false, Account{OwnerName: "Alice", AccountType: "Savings", Nickname: "Rainy day"}},
+		{"Rename owner", `{"owner_name":" Alice Smith "}`, false, Account{OwnerName: "Alice Smith", AccountType: "Savings", Nickname: "Rainy day"}},
+		{"Change type is normalized", `{"account_type":"checking"}`, false, Account{OwnerName: "Alice", AccountType: "Checking", Nickname: "Rainy day"}},
+		{"Remove nickname", `{"nickname":null}`, false, Account{OwnerName: "Alice", AccountType: "Savings"}},
+		{"Set nickname", `{"nickname":"Holidays"}`, false, Account{OwnerName: "Alice", AccountType: "Savings", Nickname: "Holidays"}},
+
+		// ❌ Fields outside the whitelist
+		{"Balance", `{"balance":{"value":"1000000.00","currency":"USD"}}`, true, Account{}},
+		{"Owner", `{"user_id":"mallory"}`, true, Account{}},
+		{"Account number", `{"account_number":"9999999999"}`, true, Account{}},
+		{"Status", `{"status":"active"}`, true, Account{}},
+
+		// ❌ Invalid values
+		{"Not an object", `["owner_name"]`, true, Account{}},
+		{"Remove owner name", `{"owner_name":null}`, true, Account{}},
+		{"Blank owner name", `{"owner_name":"  "}`, true, Account{}},
+		{"Unknown account type", `{"account_type":"Crypto"}`, true, Account{}},
+		{"Wrong type", `{"nickname":42}`, true, Account{}},
+		{"Nickname too long", `{"nickname":"` + fmt.Sprintf("%051d", 0) + `"}`, true, Account{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			account := Account{OwnerName: "Alice", AccountType: "Savings", Nickname: "Rainy day", Currency: "USD", Balance: NewMoney(500, "USD")}
+
+			patch, err := ParseAccountPatch([]byte(tc.body))
+			if err == nil {
+				err = patch.Apply(&account)
+			}
+
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if account.OwnerName != tc.expected.OwnerName || account.AccountType != tc.expected.AccountType || account.Nickname != tc.expected.Nickname {
+				t.Errorf("Expected %q/%q/%q, got %q/%q/%q", tc.expected.OwnerName, tc.expected.AccountType, tc.expected.Nickname,
+					account.OwnerName, account.AccountType, account.Nickname)
+			}
+			if account.Balance != NewMoney(500, "USD") {
+				t.Errorf("Patch must not change the balance, got %s", account.Balance)
+			}
+		})
+	}
+}
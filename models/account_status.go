@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Account statuses
+const (
+	AccountActive  = "active"
+	AccountFrozen  = "frozen"  // Set by staff; no money may leave the account
+	AccountDormant = "dormant" // Inactive; debits wait until the account is reactivated
+	AccountClosed  = "closed"  // Final; the account is soft deleted with a zero balance
+)
+
+// ErrIllegalAccountTransition is returned when an account status change is not allowed
+var ErrIllegalAccountTransition = errors.New("illegal account status transition")
+
+// Allowed account status transitions. A frozen account must be unfrozen
+// before it can be closed, so a freeze cannot be escaped by closing.
+var accountTransitions = map[string][]string{
+	AccountActive:  {AccountFrozen, AccountDormant, AccountClosed},
+	AccountFrozen:  {AccountActive},
+	AccountDormant: {AccountActive, AccountFrozen, AccountClosed},
+}
+
+// AccountStatusChange is the audit record of one account status transition
+type AccountStatusChange struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	AccountNumber string    `gorm:"type:text;not null;index" json:"account_number"`
+	From          string    `gorm:"type:text;not null" json:"from"`
+	To            string    `gorm:"type:text;not null" json:"to"`
+	Reason        string    `gorm:"type:text" json:"reason,omitempty"`
+	ChangedBy     string    `gorm:"type:text;not null" json:"changed_by"` // User ID of the customer or staff member
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// IsValidAccountStatus reports whether status is a known account status
+func IsValidAccountStatus(status string) bool {
+	switch status {
+	case AccountActive, AccountFrozen, AccountDormant, AccountClosed:
+		return true
+	}
+	return false
+}
+
+// CanTransitionAccount reports whether an account may move from one status to another
+func CanTransitionAccount(from, to string) bool {
+	for _, next := range accountTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo moves the account to a new status and returns the audit
+// record of the change.
+func (a *Account) TransitionTo(to, reason, changedBy string) (AccountStatusChange, error) {
+	if !CanTransitionAccount(a.Status, to) {
+		return AccountStatusChange{}, fmt.Errorf("%w: %q to %q", ErrIllegalAccountTransition, a.Status, to)
+	}
+
+	change := AccountStatusChange{AccountNumber: a.AccountNumber, From: a.Status, To: to, Reason: reason, ChangedBy: changedBy}
+	a.Status = to
+	return change, nil
+}
+
+// AllowsDebits reports whether money may leave the account
+func (a *Account) AllowsDebits() bool {
+	return a.Status == AccountActive
+}
+
+// AllowsCredits reports whether money may enter the account
+func (a *Account) AllowsCredits() bool {
+	return a.Status != AccountClosed
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestAccountStatusTransitions tests the account lifecycle state machine
+func TestAccountStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		from        string
+		to          string
+		expectError bool
+	}{
+		// ✅ Legal transitions
+		{"Freeze", AccountActive, AccountFrozen, false},
+		{"Unfreeze", AccountFrozen, AccountActive, false},
+		{"Go dormant", AccountActive, AccountDormant, false},
+		{"Reactivate", AccountDormant, AccountActive, false},
+		{"Freeze dormant account", AccountDormant, AccountFrozen, false},
+		{"Close active account", AccountActive, AccountClosed, false},
+		{"Close dormant account", AccountDormant, AccountClosed, false},
+
+		// ❌ Illegal transitions
+		{"Frozen account cannot close", AccountFrozen, AccountClosed, true},
+		{"Frozen account cannot go dormant", AccountFrozen, AccountDormant, true},
+		{"Closed is final", AccountClosed, AccountActive, true},
+		{"Unknown status", AccountActive, "suspended", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			account := Account{AccountNumber: "1111111111", Status: tc.from}
+			change, err := account.TransitionTo(tc.to, "test", "staff-1")
+
+			if tc.expectError {
+				if !errors.Is(err, ErrIllegalAccountTransition) {
+					t.Fatalf("Expected illegal transition error, got %v", err)
+				}
+				if account.Status != tc.from {
+					t.Errorf("Rejected transition must not change the account, got status %q", account.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if account.Status != tc.to || change.From != tc.from || change.To != tc.to || change.ChangedBy != "staff-1" {
+				t.Errorf("Unexpected change %+v, status %q", change, account.Status)
+			}
+		})
+	}
+}
+
+// TestAccountAllowsMoney tests which statuses accept debits and credits
+func TestAccountAllowsMoney(t *testing.T) {
+	tests := []struct {
+		status  string
+		debits  bool
+		credits bool
+	}{
+		{AccountActive, true, true},
+		{AccountFrozen, false, true},
+		{AccountDormant, false, true},
+		{AccountClosed, false, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.status, func(t *testing.T) {
+			fmt.Println("Running test:", tc.status) // Print log
+			account := Account{Status: tc.status}
+			if account.AllowsDebits() != tc.debits || account.AllowsCredits() != tc.credits {
+				t.Errorf("Expected debits=%v credits=%v", tc.debits, tc.credits)
+			}
+		})
+	}
+}
@@ -20,7 +20,7 @@ func TestAccountValidation(t *testing.T) {
 				AccountNumber: "123456",
 				OwnerName:     "John Doe",
 				AccountType:   "Savings", // ✅ Set a valid account type
-				Balance:       1000.00,
+				Balance:       NewMoney(100000, "USD"),
 				Currency:      "USD",
 				CreatedAt:     time.Now(),
 			},
@@ -33,7 +33,7 @@ func TestAccountValidation(t *testing.T) {
 			account: Account{
 				OwnerName:   "Alice",
 				AccountType: "Checking", // ✅ Ensure account type is set
-				Balance:     500.00,
+				Balance:     NewMoney(50000, "USD"),
 				Currency:    "USD",
 			},
 			expectError: true,
@@ -46,7 +46,7 @@ func TestAccountValidation(t *testing.T) {
 				AccountNumber: "678901",
 				OwnerName:     "Bob",
 				AccountType:   "Business", // ✅ Set a valid account type
-				Balance:       -100.00,
+				Balance:       NewMoney(-10000, "EUR"),
 				Currency:      "EUR",
 			},
 			expectError: true,
@@ -58,7 +58,7 @@ func TestAccountValidation(t *testing.T) {
 			account: Account{
 				AccountNumber: "999999",
 				AccountType:   "Savings", // ✅ Ensure account type is set
-				Balance:       100.00,
+				Balance:       NewMoney(10000, "GBP"),
 				Currency:      "GBP",
 			},
 			expectError: true,
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BalanceSnapshot records the balance of an account from every posting made
+// before AsOf. Postings are append-only, so a snapshot never goes stale and
+// historical balances only need to replay the postings made after one.
+type BalanceSnapshot struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	AccountNumber string    `gorm:"type:text;not null;uniqueIndex:idx_balance_snapshots_account_as_of" json:"account_number"`
+	AsOf          time.Time `gorm:"not null;uniqueIndex:idx_balance_snapshots_account_as_of" json:"as_of"`
+	Balance       Money     `gorm:"not null" json:"balance"`
+	Currency      string    `gorm:"type:text;not null" json:"currency"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// BeforeSave keeps the currency column in step with the balance.
+func (s *BalanceSnapshot) BeforeSave(tx *gorm.DB) (err error) {
+	s.Currency = s.Balance.Currency
+	return nil
+}
+
+// AfterFind attaches the currency column to the balance after loading.
+func (s *BalanceSnapshot) AfterFind(tx *gorm.DB) (err error) {
+	s.Balance.Currency = s.Currency
+	return nil
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the outcome of a money-moving request so that a
+// retried request with the same Idempotency-Key header is replayed instead of
+// executed twice. Keys are scoped to the user that sent them.
+type IdempotencyKey struct {
+	UserID       string     `gorm:"type:text;primaryKey" json:"user_id"`
+	Key          string     `gorm:"type:text;primaryKey" json:"key"`
+	Method       string     `gorm:"type:text;not null" json:"method"`
+	Path         string     `gorm:"type:text;not null" json:"path"`
+	Fingerprint  string     `gorm:"type:text;not null" json:"fingerprint"`
+	StatusCode   int        `gorm:"not null;default:0" json:"status_code"`
+	ResponseBody []byte     `json:"-"`
+	CreatedAt    time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	LeaseToken   string     `gorm:"type:text" json:"-"`    // Identifies the request holding the key
+	LeaseUntil   *time.Time `json:"lease_until,omitempty"` // An unfinished request past this is taken as abandoned
+	CompletedAt  *time.Time `json:"completed_at"`
+}
+
+// Completed reports whether the original request has finished and its
+// response has been stored.
+func (k *IdempotencyKey) Completed() bool {
+	return k.CompletedAt != nil
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Posting directions
+const (
+	Debit  = "debit"
+	Credit = "credit"
+)
+
+// SettlementAccount is the internal ledger account that funds deposits and
+// receives withdrawals. It has no row in the accounts table.
+const SettlementAccount = "SETTLEMENT"
+
+// FXAccount is the internal ledger account that sells one currency for
+// another in cross-currency transfers. It has no row in the accounts table.
+const FXAccount = "FX"
+
+// IsInternalAccount reports whether the account exists only in the journal.
+func IsInternalAccount(accountNumber string) bool {
+	return accountNumber == SettlementAccount || accountNumber == FXAccount
+}
+
+// JournalEntry is a balanced set of postings recording a single ledger event.
+// Journal entries are append-only; account balances are derived from them.
+type JournalEntry struct {
+	ID          string    `gorm:"type:text;primaryKey" json:"id"`
+	Type        string    `gorm:"type:text;not null" json:"type"`
+	Reference   string    `gorm:"type:text;index" json:"reference"`
+	Description string    `gorm:"type:text" json:"description"`
+	Postings    []Posting `gorm:"foreignKey:JournalEntryID" json:"postings"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// IdempotencyKey is unique across the journal, so a request or message
+	// carrying an already-used key can never be posted twice.
+	IdempotencyKey *string `gorm:"type:text;uniqueIndex" json:"idempotency_key,omitempty"`
+}
+
+// Posting is a single debit or credit against one ledger account.
+type Posting struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	JournalEntryID string    `gorm:"type:text;not null;index" json:"journal_entry_id"`
+	AccountNumber  string    `gorm:"type:text;not null;index" json:"account_number"`
+	Direction      string    `gorm:"type:text;not null" json:"direction"`
+	Amount         Money     `gorm:"not null" json:"amount"`
+	Currency       string    `gorm:"type:text;not null" json:"currency"`
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// BeforeCreate runs before inserting a new journal entry.
+func (e *JournalEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// BeforeSave keeps the currency column in step with the posting amount.
+func (p *Posting) BeforeSave(tx *gorm.DB) (err error) {
+	p.Currency = p.Amount.Currency
+	return nil
+}
+
+// AfterFind attaches the currency column to the posting amount after loading.
+func (p *Posting) AfterFind(tx *gorm.DB) (err error) {
+	p.Amount.Currency = p.Currency
+	return nil
+}
+
+// Validate ensures the entry is well formed and that debits equal credits.
+func (e *JournalEntry) Validate() error {
+	if e.Type == "" {
+		return errors.New("journal entry type is required")
+	}
+
+	if len(e.Postings) < 2 {
+		return errors.New("journal entry needs at least two postings")
+	}
+
+	// Debits must equal credits in every currency; a currency conversion
+	// balances each side against the FX account
+	debits, credits, net := map[string]int64{}, map[string]int64{}, map[string]int64{}
+	for _, p := range e.Postings {
+		if p.AccountNumber == "" {
+			return errors.New("posting account number is required")
+		}
+		if !p.Amount.IsPositive() {
+			return errors.New("posting amount must be greater than zero")
+		}
+		if !IsSupportedCurrency(p.Amount.Currency) {
+			return fmt.Errorf("unsupported posting currency: %s", p.Amount.Currency)
+		}
+
+		switch p.Direction {
+		case Debit:
+			debits[p.Amount.Currency] += p.Amount.MinorUnits
+			net[p.Amount.Currency] -= p.Amount.MinorUnits
+		case Credit:
+			credits[p.Amount.Currency] += p.Amount.MinorUnits
+			net[p.Amount.Currency] += p.Amount.MinorUnits
+		default:
+			return fmt.Errorf("invalid posting direction: %s", p.Direction)
+		}
+	}
+
+	for currency, balance := range net {
+		if balance != 0 {
+			return fmt.Errorf("unbalanced journal entry: debits %s != credits %s",
+				NewMoney(debits[currency], currency), NewMoney(credits[currency], currency))
+		}
+	}
+
+	return nil
+}
+
+// SignedAmount returns the effect of the posting on a customer account
+// balance. Customer accounts are liabilities, so credits increase them.
+func (p *Posting) SignedAmount() Money {
+	if p.Direction == Debit {
+		return p.Amount.Neg()
+	}
+	return p.Amount
+}
+
+// NewDepositEntry moves funds from settlement into a customer account.
+func NewDepositEntry(accountNumber string, amount Money, reference string) *JournalEntry {
+	return newEntry("deposit", reference, SettlementAccount, accountNumber, amount)
+}
+
+// NewWithdrawalEntry moves funds from a customer account out to settlement.
+func NewWithdrawalEntry(accountNumber string, amount Money, reference string) *JournalEntry {
+	return newEntry("withdrawal", reference, accountNumber, SettlementAccount, amount)
+}
+
+// NewTransferEntry moves funds between two customer accounts.
+func NewTransferEntry(source, destination string, amount Money, reference string) *JournalEntry {
+	return newEntry("transfer", reference, source, destination, amount)
+}
+
+// NewFXTransferEntry moves funds between two customer accounts held in
+// different currencies. The source is debited in its currency and the
+// destination credited in its own, with the FX account taking both sides.
+func NewFXTransferEntry(source, destination string, debit, credit Money, reference string) *JournalEntry {
+	return &JournalEntry{
+		Type:      "transfer",
+		Reference: reference,
+		Postings: []Posting{
+			{AccountNumber: source, Direction: Debit, Amount: debit},
+			{AccountNumber: FXAccount, Direction: Credit, Amount: debit},
+			{AccountNumber: FXAccount, Direction: Debit, Amount: credit},
+			{AccountNumber: destination, Direction: Credit, Amount: credit},
+		},
+	}
+}
+
+// newEntry builds a two-legged entry debiting one account and crediting another.
+func newEntry(entryType, reference, debitAccount, creditAccount string, amount Money) *JournalEntry {
+	return &JournalEntry{
+		Type:      entryType,
+		Reference: reference,
+		Postings: []Posting{
+			{AccountNumber: debitAccount, Direction: Debit, Amount: amount},
+			{AccountNumber: creditAccount, Direction: Credit, Amount: amount},
+		},
+	}
+}
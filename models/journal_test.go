@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestJournalEntryValidation tests that only balanced entries are accepted
+func TestJournalEntryValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		entry       *JournalEntry
+		expectError bool
+	}{
+		// ✅ Valid Deposit Entry
+		{
+			name:        "Valid deposit entry",
+			entry:       NewDepositEntry("12345", NewMoney(10000, "USD"), ""),
+			expectError: false,
+		},
+		// ✅ Valid Transfer Entry
+		{
+			name:        "Valid transfer entry",
+			entry:       NewTransferEntry("12345", "67890", NewMoney(25000, "EUR"), ""),
+			expectError: false,
+		},
+		// ✅ Valid Cross-Currency Transfer Entry (balanced per currency)
+		{
+			name:        "Valid cross-currency transfer entry",
+			entry:       NewFXTransferEntry("12345", "67890", NewMoney(10000, "USD"), NewMoney(9154, "EUR"), ""),
+			expectError: false,
+		},
+		// ❌ Unbalanced Entry
+		{
+			name: "Invalid entry (debits do not equal credits)",
+			entry: &JournalEntry{
+				Type: "transfer",
+				Postings: []Posting{
+					{AccountNumber: "12345", Direction: Debit, Amount: NewMoney(10000, "USD")},
+					{AccountNumber: "67890", Direction: Credit, Amount: NewMoney(9000, "USD")},
+				},
+			},
+			expectError: true,
+		},
+		// ❌ Single Posting
+		{
+			name: "Invalid entry (single posting)",
+			entry: &JournalEntry{
+				Type: "deposit",
+				Postings: []Posting{
+					{AccountNumber: "12345", Direction: Credit, Amount: NewMoney(10000, "USD")},
+				},
+			},
+			expectError: true,
+		},
+		// ❌ Mixed Currencies
+		{
+			name: "Invalid entry (mixed currencies)",
+			entry: &JournalEntry{
+				Type: "transfer",
+				Postings: []Posting{
+					{AccountNumber: "12345", Direction: Debit, Amount: NewMoney(10000, "USD")},
+					{AccountNumber: "67890", Direction: Credit, Amount: NewMoney(10000, "EUR")},
+				},
+			},
+			expectError: true,
+		},
+		// ❌ Zero Amount
+		{
+			name:        "Invalid entry (zero amount)",
+			entry:       NewWithdrawalEntry("12345", NewMoney(0, "USD"), ""),
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			err := tc.entry.Validate()
+
+			// Check if error status matches expectation
+			if (err != nil) != tc.expectError {
+				t.Errorf("Test case '%s' failed: expected error %v, got %v", tc.name, tc.expectError, err)
+			}
+		})
+	}
+}
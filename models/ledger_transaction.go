@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LedgerTransaction is the PostgreSQL record of a posted deposit, withdrawal,
+// transfer or reversal. It is written in the same database transaction as
+// its journal entry, so it always agrees with account balances.
+type LedgerTransaction struct {
+	ID                 string        `gorm:"type:text;primaryKey" json:"id"`
+	JournalEntryID     string        `gorm:"type:text;not null;uniqueIndex" json:"journal_entry_id"`
+	Type               string        `gorm:"type:text;not null" json:"type"`
+	Status             string        `gorm:"type:text;not null" json:"status"`
+	AccountNumber      string        `gorm:"type:text;index" json:"account_number,omitempty"`
+	SourceAccount      string        `gorm:"type:text;index" json:"source_account,omitempty"`
+	DestinationAccount string        `gorm:"type:text;index" json:"destination_account,omitempty"`
+	Amount             Money         `gorm:"not null" json:"amount"`
+	Currency           string        `gorm:"type:text;not null" json:"currency"`
+	FX                 *FXConversion `gorm:"serializer:json" json:"fx,omitempty"`
+	Reference          string        `gorm:"type:text;index" json:"reference,omitempty"` // Journal entry a reversal undoes
+	CreatedAt          time.Time     `gorm:"not null;index" json:"created_at"`
+}
+
+// BeforeCreate runs before inserting a new ledger transaction.
+func (t *LedgerTransaction) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	if t.Status == "" {
+		t.Status = StatusCompleted
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// BeforeSave keeps the currency column in step with the amount.
+func (t *LedgerTransaction) BeforeSave(tx *gorm.DB) (err error) {
+	t.Currency = t.Amount.Currency
+	return nil
+}
+
+// AfterFind attaches the currency column to the amount after loading.
+func (t *LedgerTransaction) AfterFind(tx *gorm.DB) (err error) {
+	t.Amount.Currency = t.Currency
+	return nil
+}
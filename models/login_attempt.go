@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Login attempt outcomes
+const (
+	LoginSucceeded          = "succeeded"
+	LoginInvalidCredentials = "invalid_credentials"
+	LoginLockedOut          = "locked_out"
+	LoginMFARequired        = "mfa_required" // Password accepted; waiting for the second factor
+	LoginInvalidMFACode     = "invalid_mfa_code"
+)
+
+// LoginAttempt is the audit record of one call to /api/login. UserID is
+// empty when the username does not exist.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Username  string    `gorm:"type:text;not null;index" json:"username"`
+	UserID    string    `gorm:"type:text;index" json:"user_id,omitempty"`
+	IP        string    `gorm:"type:text;not null;index" json:"ip"`
+	UserAgent string    `gorm:"type:text" json:"user_agent"`
+	Outcome   string    `gorm:"type:text;not null" json:"outcome"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// MFAFactor is a user's TOTP authenticator. It only guards logins once it
+// has been confirmed with a first valid code.
+type MFAFactor struct {
+	ID           uint       `gorm:"primaryKey" json:"-"`
+	UserID       string     `gorm:"type:text;not null;uniqueIndex" json:"-"`
+	Secret       string     `gorm:"type:text;not null" json:"-"` // Base32, as shared with the authenticator app
+	LastUsedStep int64      `gorm:"not null;default:0" json:"-"` // Time step of the last accepted code, so codes cannot be replayed
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	CreatedAt    time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Confirmed reports whether enrollment was completed
+func (f *MFAFactor) Confirmed() bool {
+	return f.ConfirmedAt != nil
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the
+// authenticator is lost. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"type:text;not null;index"`
+	CodeHash  string `gorm:"type:text;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// currencyExponents maps supported ISO 4217 codes to their number of decimal places
+var currencyExponents = map[string]int{
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"INR": 2,
+	"JPY": 0,
+}
+
+// maxIntegerDigits bounds parsed amounts so minor units always fit in an int64
+const maxIntegerDigits = 15
+
+var decimalRegex = regexp.MustCompile(`^(-)?(\d+)(?:\.(\d+))?$`)
+
+// Money is an exact monetary amount held as integer minor units of a currency
+// (cents for USD, yen for JPY). It never passes through float64.
+type Money struct {
+	MinorUnits int64  `bson:"minor_units"`
+	Currency   string `bson:"currency"`
+}
+
+// IsSupportedCurrency reports whether the ISO 4217 code is supported.
+func IsSupportedCurrency(currency string) bool {
+	_, ok := currencyExponents[currency]
+	return ok
+}
+
+// SupportedCurrencies returns the supported ISO 4217 codes in sorted order.
+func SupportedCurrencies() []string {
+	currencies := make([]string, 0, len(currencyExponents))
+	for currency := range currencyExponents {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// CurrencyExponent returns the number of decimal places used by a currency.
+func CurrencyExponent(currency string) (int, error) {
+	exp, ok := currencyExponents[currency]
+	if !ok {
+		return 0, fmt.Errorf("unsupported currency: %s", currency)
+	}
+	return exp, nil
+}
+
+// NewMoney creates a Money value from minor units.
+func NewMoney(minorUnits int64, currency string) Money {
+	return Money{MinorUnits: minorUnits, Currency: currency}
+}
+
+// ParseMoney parses a decimal string such as "12.50" in the given currency.
+// Amounts with more significant decimal places than the currency allows are
+// rejected.
+func ParseMoney(amount string, currency string) (Money, error) {
+	exp, err := CurrencyExponent(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	parts := decimalRegex.FindStringSubmatch(strings.TrimSpace(amount))
+	if parts == nil {
+		return Money{}, fmt.Errorf("invalid amount: %q", amount)
+	}
+
+	sign, whole, frac := parts[1], strings.TrimLeft(parts[2], "0"), parts[3]
+	if len(whole) > maxIntegerDigits {
+		return Money{}, errors.New("amount is too large")
+	}
+	if len(frac) > exp {
+		// Trailing zeros beyond the currency exponent carry no value
+		if strings.TrimRight(frac[exp:], "0") != "" {
+			return Money{}, fmt.Errorf("amount has too many decimal places for %s (max %d)", currency, exp)
+		}
+		frac = frac[:exp]
+	}
+
+	digits := whole + frac + strings.Repeat("0", exp-len(frac))
+	if digits == "" {
+		digits = "0"
+	}
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount: %q", amount)
+	}
+	if sign == "-" {
+		minor = -minor
+	}
+
+	return NewMoney(minor, currency), nil
+}
+
+// Decimal formats the amount as a decimal string without the currency code.
+func (m Money) Decimal() string {
+	exp := currencyExponents[m.Currency]
+	sign := ""
+	minor := m.MinorUnits
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	digits := strconv.FormatInt(minor, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// String formats the amount with its currency code, e.g. "12.50 USD".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool { return m.MinorUnits == 0 }
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool { return m.MinorUnits > 0 }
+
+// IsNegative reports whether the amount is less than zero.
+func (m Money) IsNegative() bool { return m.MinorUnits < 0 }
+
+// Neg returns the amount with its sign flipped.
+func (m Money) Neg() Money { return NewMoney(-m.MinorUnits, m.Currency) }
+
+// Add returns m + other. Both amounts must share a currency.
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return NewMoney(m.MinorUnits+other.MinorUnits, m.Currency), nil
+}
+
+// Sub returns m - other. Both amounts must share a currency.
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return NewMoney(m.MinorUnits-other.MinorUnits, m.Currency), nil
+}
+
+// Cmp returns -1, 0 or 1 when m is less than, equal to or greater than other.
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.MinorUnits < other.MinorUnits:
+		return -1, nil
+	case m.MinorUnits > other.MinorUnits:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (m Money) sameCurrency(other Money) error {
+	if m.Currency != other.Currency {
+		return fmt.Errorf("currency mismatch: %s and %s", m.Currency, other.Currency)
+	}
+	return nil
+}
+
+// moneyJSON is the wire format of Money: {"value": "12.50", "currency": "USD"}
+type moneyJSON struct {
+	Value    json.RawMessage `json:"value"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as a decimal string so clients never see floats.
+func (m Money) MarshalJSON() ([]byte, error) {
+	value, _ := json.Marshal(m.Decimal())
+	return json.Marshal(moneyJSON{Value: value, Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the value as either a decimal string or a JSON number.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	parsed, err := ParseMoney(DecimalText(raw.Value), raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// DecimalText returns the literal text of a JSON number or string, so amounts
+// can be parsed exactly without being decoded into a float64 first.
+func DecimalText(raw json.RawMessage) string {
+	text := strings.TrimSpace(string(raw))
+	if text == "" || text == "null" {
+		return "0"
+	}
+	return strings.Trim(text, `"`)
+}
+
+// Value stores the amount as a BIGINT of minor units. The currency lives in
+// a sibling column on the owning model.
+func (m Money) Value() (driver.Value, error) {
+	return m.MinorUnits, nil
+}
+
+// Scan reads minor units from a BIGINT column.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		m.MinorUnits = v
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		minor, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot scan %q into Money: %w", v, err)
+		}
+		m.MinorUnits = minor
+	case nil:
+		m.MinorUnits = 0
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+// GormDataType tells GORM to store Money in a BIGINT column.
+func (Money) GormDataType() string {
+	return "bigint"
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// TestParseMoney tests exact decimal parsing with per-currency exponents
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      string
+		currency    string
+		expected    Money
+		expectError bool
+	}{
+		// ✅ Valid Amounts
+		{name: "Whole dollars", amount: "100", currency: "USD", expected: NewMoney(10000, "USD")},
+		{name: "Dollars and cents", amount: "12.34", currency: "USD", expected: NewMoney(1234, "USD")},
+		{name: "Single decimal place", amount: "0.1", currency: "EUR", expected: NewMoney(10, "EUR")},
+		{name: "Negative amount", amount: "-5.05", currency: "GBP", expected: NewMoney(-505, "GBP")},
+		{name: "Yen has no decimals", amount: "1500", currency: "JPY", expected: NewMoney(1500, "JPY")},
+
+		// ❌ Invalid Amounts
+		{name: "Too many decimals", amount: "1.005", currency: "USD", expectError: true},
+		{name: "Yen with decimals", amount: "1500.5", currency: "JPY", expectError: true},
+		{name: "Unsupported currency", amount: "10", currency: "XYZ", expectError: true},
+		{name: "Not a number", amount: "ten", currency: "USD", expectError: true},
+		{name: "Exponent notation", amount: "1e3", currency: "USD", expectError: true},
+		{name: "Too large", amount: "10000000000000000", currency: "USD", expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			got, err := ParseMoney(tc.amount, tc.currency)
+
+			// Check if error status matches expectation
+			if (err != nil) != tc.expectError {
+				t.Fatalf("Test case '%s' failed: expected error %v, got %v", tc.name, tc.expectError, err)
+			}
+			if !tc.expectError && got != tc.expected {
+				t.Errorf("Test case '%s' failed: expected %+v, got %+v", tc.name, tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestMoneyDecimal tests formatting of minor units
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money    Money
+		expected string
+	}{
+		{NewMoney(1234, "USD"), "12.34"},
+		{NewMoney(5, "USD"), "0.05"},
+		{NewMoney(-505, "GBP"), "-5.05"},
+		{NewMoney(0, "EUR"), "0.00"},
+		{NewMoney(1500, "JPY"), "1500"},
+	}
+
+	for _, tc := range tests {
+		if got := tc.money.Decimal(); got != tc.expected {
+			t.Errorf("Decimal of %d %s: expected %s, got %s", tc.money.MinorUnits, tc.money.Currency, tc.expected, got)
+		}
+	}
+}
+
+// TestMoneyArithmetic tests that repeated additions do not drift
+func TestMoneyArithmetic(t *testing.T) {
+	total := NewMoney(0, "USD")
+	dime := NewMoney(10, "USD")
+	for i := 0; i < 1000; i++ {
+		total, _ = total.Add(dime)
+	}
+	if total != NewMoney(10000, "USD") {
+		t.Errorf("Expected 100.00 USD after 1000 dimes, got %s", total)
+	}
+
+	if _, err := total.Add(NewMoney(100, "EUR")); err == nil {
+		t.Error("Expected adding EUR to USD to fail")
+	}
+	if _, err := total.Cmp(NewMoney(100, "JPY")); err == nil {
+		t.Error("Expected comparing USD to JPY to fail")
+	}
+}
+
+// TestMoneyJSON tests that amounts round-trip through JSON as decimal strings
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(NewMoney(1050, "USD"))
+	if err != nil {
+		t.Fatalf("Failed to marshal money: %v", err)
+	}
+	if string(data) != `{"value":"10.50","currency":"USD"}` {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+
+	var fromNumber Money
+	if err := json.Unmarshal([]byte(`{"value":10.5,"currency":"USD"}`), &fromNumber); err != nil {
+		t.Fatalf("Failed to unmarshal numeric value: %v", err)
+	}
+	if fromNumber != NewMoney(1050, "USD") {
+		t.Errorf("Expected 10.50 USD, got %s", fromNumber)
+	}
+
+	var req TransferRequest
+	body := `{"source_account":"1","destination_account":"2","amount":1000.00,"currency":"JPY"}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("Failed to unmarshal transfer request: %v", err)
+	}
+	if req.Amount != NewMoney(1000, "JPY") {
+		t.Errorf("Expected 1000 JPY, got %s", req.Amount)
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// OutboxMessage is a message waiting to be published to RabbitMQ. It is
+// written in the same database transaction as the ledger change it
+// describes, and a relay publishes it once that transaction has committed.
+type OutboxMessage struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Exchange    string     `gorm:"type:text;not null;default:''" json:"exchange"`
+	RoutingKey  string     `gorm:"type:text;not null" json:"routing_key"`
+	MessageID   string     `gorm:"type:text;not null;uniqueIndex" json:"message_id"`
+	ContentType string     `gorm:"type:text;not null" json:"content_type"`
+	Payload     []byte     `gorm:"not null" json:"-"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	ClaimToken  string     `gorm:"type:text" json:"-"`   // Relay run that is publishing the message
+	ClaimedAt   *time.Time `json:"claimed_at,omitempty"` // In flight until the claim expires
+	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	SentAt      *time.Time `gorm:"index" json:"sent_at,omitempty"`
+}
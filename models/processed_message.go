@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ProcessedMessage records a queue message whose ledger effects have been
+// committed. It is written in the same database transaction as the journal
+// entry, so a redelivered message can be recognised and skipped.
+type ProcessedMessage struct {
+	MessageID      string    `gorm:"type:text;primaryKey" json:"message_id"`
+	Queue          string    `gorm:"type:text;not null" json:"queue"`
+	JournalEntryID string    `gorm:"type:text" json:"journal_entry_id"`
+	ProcessedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"processed_at"`
+}
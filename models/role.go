@@ -0,0 +1,47 @@
+package models
+
+// User roles
+const (
+	RoleCustomer = "customer"
+	RoleTeller   = "teller"
+	RoleAdmin    = "admin"
+)
+
+// Permissions granted to staff roles
+const (
+	PermissionSearchAccounts      = "accounts:search"
+	PermissionFreezeAccounts      = "accounts:freeze"
+	PermissionManageAccounts      = "accounts:manage"
+	PermissionReverseTransactions = "transactions:reverse"
+	PermissionManageUsers         = "users:manage"
+)
+
+// rolePermissions maps each role to what it may do beyond managing its own
+// accounts. Customers have no extra permissions.
+var rolePermissions = map[string][]string{
+	RoleCustomer: {},
+	RoleTeller:   {PermissionSearchAccounts, PermissionFreezeAccounts},
+	RoleAdmin: {
+		PermissionSearchAccounts,
+		PermissionFreezeAccounts,
+		PermissionManageAccounts,
+		PermissionReverseTransactions,
+		PermissionManageUsers,
+	},
+}
+
+// IsValidRole reports whether the role exists.
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether the role grants the permission.
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestHasPermission tests which roles may perform staff actions
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       string
+		permission string
+		expected   bool
+	}{
+		// ✅ Staff permissions
+		{"Teller can search accounts", RoleTeller, PermissionSearchAccounts, true},
+		{"Teller can freeze accounts", RoleTeller, PermissionFreezeAccounts, true},
+		{"Admin can reverse transactions", RoleAdmin, PermissionReverseTransactions, true},
+		{"Admin can manage users", RoleAdmin, PermissionManageUsers, true},
+		// ❌ Missing permissions
+		{"Teller cannot reverse transactions", RoleTeller, PermissionReverseTransactions, false},
+		{"Teller cannot manage users", RoleTeller, PermissionManageUsers, false},
+		{"Customer cannot search accounts", RoleCustomer, PermissionSearchAccounts, false},
+		{"Unknown role has no permissions", "superuser", PermissionSearchAccounts, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			if got := HasPermission(tc.role, tc.permission); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
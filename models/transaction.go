@@ -1,9 +1,14 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -14,16 +19,63 @@ var validTransactionTypes = map[string]bool{
 	"transfer":   true,
 }
 
-// Allowed currencies
-var validCurrencies = map[string]bool{
-	"USD": true, "EUR": true, "GBP": true, "INR": true, "JPY": true,
+// TransferRequest is the payload of a transfer. Amount and currency arrive as
+// separate JSON fields and are combined into a Money value when decoded.
+type TransferRequest struct {
+	SourceAccount      string
+	DestinationAccount string
+	Amount             Money
 }
 
-type TransferRequest struct {
-	SourceAccount      string  `json:"source_account"`
-	DestinationAccount string  `json:"destination_account"`
-	Amount             float64 `json:"amount"`
-	Currency           string  `json:"currency"`
+// UnmarshalJSON parses {"amount": 10.50, "currency": "USD", ...} without
+// routing the amount through a float64.
+func (r *TransferRequest) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		SourceAccount      string          `json:"source_account"`
+		DestinationAccount string          `json:"destination_account"`
+		Amount             json.RawMessage `json:"amount"`
+		Currency           string          `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	amount, err := ParseMoney(DecimalText(raw.Amount), raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	r.SourceAccount = raw.SourceAccount
+	r.DestinationAccount = raw.DestinationAccount
+	r.Amount = amount
+	return nil
+}
+
+// AccountTransactionRequest is the payload of a deposit or withdrawal.
+type AccountTransactionRequest struct {
+	AccountNumber string
+	Amount        Money
+}
+
+// UnmarshalJSON parses {"account_number": "...", "amount": 10.50, "currency": "USD"}.
+func (r *AccountTransactionRequest) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		AccountNumber string          `json:"account_number"`
+		Amount        json.RawMessage `json:"amount"`
+		Currency      string          `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	amount, err := ParseMoney(DecimalText(raw.Amount), raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	r.AccountNumber = raw.AccountNumber
+	r.Amount = amount
+	return nil
 }
 
 // Transaction represents a bank transaction stored in MongoDB
@@ -32,15 +84,63 @@ type Transaction struct {
 	SourceAccount      string             `bson:"source_account"`
 	DestinationAccount string             `bson:"destination_account"`
 	AccountNumber      string             `bson:"account_number"`
-	Amount             float64            `bson:"amount"`
-	Currency           string             `bson:"currency"`
+	Amount             Money              `bson:"amount"`
 	Type               string             `bson:"type"`
 	Status             string             `bson:"status"`
 	Reference          string             `bson:"reference"`
+	IdempotencyKey     string             `bson:"idempotency_key,omitempty"`
+	FailureReason      string             `bson:"failure_reason,omitempty"`
+	FX                 *FXConversion      `bson:"fx,omitempty"`
+	ReversalReference  string             `bson:"reversal_reference,omitempty"`
+	StatusHistory      []StatusChange     `bson:"status_history"`
 	CreatedAt          time.Time          `bson:"created_at"`
 	UpdatedAt          time.Time          `bson:"updated_at"`
 }
 
+// UnmarshalBSON decodes a stored transaction. Documents written before
+// amounts were stored as Money hold the amount as a double and the currency
+// in a separate field; those are converted on read.
+func (t *Transaction) UnmarshalBSON(data []byte) error {
+	type Stored Transaction // Same fields without this method, so decoding does not recurse
+	var raw struct {
+		Stored         `bson:",inline"`
+		Amount         bson.RawValue `bson:"amount"`
+		LegacyCurrency string        `bson:"currency"`
+	}
+	if err := bson.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*t = Transaction(raw.Stored)
+	switch raw.Amount.Type {
+	case 0: // No amount
+	case bsontype.EmbeddedDocument:
+		return raw.Amount.Unmarshal(&t.Amount)
+	case bsontype.Double:
+		exp, err := CurrencyExponent(raw.LegacyCurrency)
+		if err != nil {
+			return err
+		}
+		amount, err := ParseMoney(strconv.FormatFloat(raw.Amount.Double(), 'f', exp, 64), raw.LegacyCurrency)
+		if err != nil {
+			return err
+		}
+		t.Amount = amount
+	default:
+		return fmt.Errorf("cannot decode transaction amount from BSON %s", raw.Amount.Type)
+	}
+	return nil
+}
+
+// FXConversion records the currency conversion applied to a transfer between
+// accounts held in different currencies.
+type FXConversion struct {
+	Rate              string `bson:"rate"`   // Mid-market rate from the source to the destination currency
+	Spread            string `bson:"spread"` // Fraction of the rate kept as margin
+	SourceAmount      Money  `bson:"source_amount"`
+	DestinationAmount Money  `bson:"destination_amount"`
+}
+
 // Validate checks if the transaction data is valid
 func (t *Transaction) Validate() error {
 	// Validate transaction type
@@ -49,12 +149,12 @@ func (t *Transaction) Validate() error {
 	}
 
 	// Validate amount (must be positive)
-	if t.Amount <= 0 {
+	if !t.Amount.IsPositive() {
 		return errors.New("amount must be greater than zero")
 	}
 
 	// Validate currency
-	if t.Currency != "" && !validCurrencies[t.Currency] {
+	if !IsSupportedCurrency(t.Amount.Currency) {
 		return errors.New("invalid currency: must be a supported currency (e.g., USD, EUR, GBP)")
 	}
 
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Page sizes for transaction listings
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// TransactionQuery holds the filters and page position of a transaction
+// listing. Results are ordered by creation time, then ID, so every
+// transaction has a stable position for cursor pagination.
+type TransactionQuery struct {
+	AccountNumbers []string   // Transactions touching any of these accounts
+	From           *time.Time // Created at or after
+	To             *time.Time // Created before
+	Type           string
+	Status         string
+	Currency       string
+	MinAmount      *Money
+	MaxAmount      *Money
+	Counterparty   string // The other account of a transfer
+	Ascending      bool
+	Limit          int
+	After          *PageCursor // Position of the last item of the previous page
+}
+
+// Accounts returns the account numbers the query is limited to, without
+// blanks. Transactions leave the account fields they do not use empty, so a
+// blank account number would match every transaction.
+func (q TransactionQuery) Accounts() []string {
+	accounts := make([]string, 0, len(q.AccountNumbers))
+	for _, number := range q.AccountNumbers {
+		if number != "" {
+			accounts = append(accounts, number)
+		}
+	}
+	return accounts
+}
+
+// PageCursor marks a position in a listing. It is handed to clients as an
+// opaque token.
+type PageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// Encode returns the cursor as an opaque URL-safe token.
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageCursor parses a token produced by Encode.
+func DecodePageCursor(token string) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cursor PageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// ParseTransactionQuery reads listing filters from URL query parameters:
+// from, to (RFC 3339 or YYYY-MM-DD), type, status, currency, min_amount,
+// max_amount (decimal, requires currency), counterparty, sort (asc|desc),
+// limit and cursor. Account numbers are set by the caller after checking
+// ownership.
+func ParseTransactionQuery(values url.Values) (TransactionQuery, error) {
+	q := TransactionQuery{
+		Type:         values.Get("type"),
+		Status:       values.Get("status"),
+		Currency:     values.Get("currency"),
+		Counterparty: values.Get("counterparty"),
+		Limit:        DefaultPageSize,
+	}
+
+	var err error
+	if q.From, err = ParseTime(values.Get("from")); err != nil {
+		return q, fmt.Errorf("invalid from: %w", err)
+	}
+	if q.To, err = ParseTime(values.Get("to")); err != nil {
+		return q, fmt.Errorf("invalid to: %w", err)
+	}
+
+	if q.Type != "" && !validTransactionTypes[q.Type] && q.Type != "reversal" {
+		return q, errors.New("invalid type: must be 'deposit', 'withdrawal', 'transfer' or 'reversal'")
+	}
+	if q.Status != "" && !IsValidStatus(q.Status) {
+		return q, fmt.Errorf("invalid status: %s", q.Status)
+	}
+
+	if q.MinAmount, err = parseAmountBound(values, "min_amount", q.Currency); err != nil {
+		return q, err
+	}
+	if q.MaxAmount, err = parseAmountBound(values, "max_amount", q.Currency); err != nil {
+		return q, err
+	}
+
+	switch values.Get("sort") {
+	case "", "desc":
+	case "asc":
+		q.Ascending = true
+	default:
+		return q, errors.New("invalid sort: must be 'asc' or 'desc'")
+	}
+
+	if text := values.Get("limit"); text != "" {
+		limit, err := strconv.Atoi(text)
+		if err != nil || limit <= 0 {
+			return q, errors.New("invalid limit")
+		}
+		if limit > MaxPageSize {
+			limit = MaxPageSize
+		}
+		q.Limit = limit
+	}
+
+	if token := values.Get("cursor"); token != "" {
+		if q.After, err = DecodePageCursor(token); err != nil {
+			return q, err
+		}
+	}
+
+	return q, nil
+}
+
+// parseAmountBound parses an amount filter, which is only meaningful in a
+// single currency
+func parseAmountBound(values url.Values, param, currency string) (*Money, error) {
+	text := values.Get(param)
+	if text == "" {
+		return nil, nil
+	}
+	if currency == "" {
+		return nil, fmt.Errorf("%s requires currency", param)
+	}
+	amount, err := ParseMoney(text, currency)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", param, err)
+	}
+	return &amount, nil
+}
+
+// ParseTime accepts an RFC 3339 timestamp or a plain date. Empty text gives
+// nil.
+func ParseTime(text string) (*time.Time, error) {
+	if text == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a date", text)
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestParseTransactionQuery tests reading listing filters from query parameters
+func TestParseTransactionQuery(t *testing.T) {
+	cursor := PageCursor{CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), ID: "665f1c2a9b1e8a0012345678"}.Encode()
+
+	tests := []struct {
+		name        string
+		query       string
+		expectError bool
+		check       func(q TransactionQuery) error
+	}{
+		// ✅ Valid queries
+		{"Defaults", "", false, func(q TransactionQuery) error {
+			if q.Limit != DefaultPageSize || q.Ascending || q.After != nil {
+				return fmt.Errorf("unexpected defaults: %+v", q)
+			}
+			return nil
+		}},
+		{"Date range", "from=2024-01-01&to=2024-02-01T00:00:00Z", false, func(q TransactionQuery) error {
+			if q.From == nil || !q.From.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || q.To == nil {
+				return fmt.Errorf("unexpected range: %v - %v", q.From, q.To)
+			}
+			return nil
+		}},
+		{"Amount range", "currency=USD&min_amount=10&max_amount=99.99", false, func(q TransactionQuery) error {
+			if q.MinAmount.MinorUnits != 1000 || q.MaxAmount.MinorUnits != 9999 {
+				return fmt.Errorf("unexpected amounts: %v - %v", q.MinAmount, q.MaxAmount)
+			}
+			return nil
+		}},
+		{"Ascending", "sort=asc", false, func(q TransactionQuery) error {
+			if !q.Ascending {
+				return fmt.Errorf("expected ascending order")
+			}
+			return nil
+		}},
+		{"Limit is capped", "limit=5000", false, func(q TransactionQuery) error {
+			if q.Limit != MaxPageSize {
+				return fmt.Errorf("expected limit %d, got %d", MaxPageSize, q.Limit)
+			}
+			return nil
+		}},
+		{"Cursor", "cursor=" + cursor, false, func(q TransactionQuery) error {
+			if q.After == nil || q.After.ID != "665f1c2a9b1e8a0012345678" {
+				return fmt.Errorf("unexpected cursor: %+v", q.After)
+			}
+			return nil
+		}},
+		{"Type and status", "type=transfer&status=completed&counterparty=1234567890", false, nil},
+
+		// ❌ Invalid queries
+		{"Bad date", "from=yesterday", true, nil},
+		{"Bad type", "type=loan", true, nil},
+		{"Bad status", "status=done", true, nil},
+		{"Amount without currency", "min_amount=10", true, nil},
+		{"Bad amount", "currency=USD&max_amount=ten", true, nil},
+		{"Bad sort", "sort=random", true, nil},
+		{"Bad limit", "limit=-1", true, nil},
+		{"Bad cursor", "cursor=not-a-cursor", true, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			values, _ := url.ParseQuery(tc.query)
+			q, err := ParseTransactionQuery(values)
+
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.check != nil {
+				if err := tc.check(q); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+// TestPageCursorRoundTrip tests that a cursor survives encoding
+func TestPageCursorRoundTrip(t *testing.T) {
+	cursor := PageCursor{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123000000, time.UTC), ID: "665f1c2a9b1e8a0012345678"}
+
+	decoded, err := DecodePageCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID {
+		t.Errorf("Expected %+v, got %+v", cursor, *decoded)
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Transaction statuses
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+	StatusReversed   = "reversed"
+)
+
+// ErrIllegalTransition is returned when a status change is not allowed
+var ErrIllegalTransition = errors.New("illegal transaction status transition")
+
+// Allowed status transitions; a new transaction starts from the empty status
+var transactionTransitions = map[string][]string{
+	"":               {StatusPending},
+	StatusPending:    {StatusProcessing, StatusFailed},
+	StatusProcessing: {StatusCompleted, StatusFailed},
+	StatusCompleted:  {StatusReversed},
+}
+
+// StatusChange records one transition in a transaction's lifecycle
+type StatusChange struct {
+	From   string    `bson:"from"`
+	To     string    `bson:"to"`
+	Reason string    `bson:"reason,omitempty"`
+	At     time.Time `bson:"at"`
+}
+
+// IsValidStatus reports whether status is a known transaction status
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusProcessing, StatusCompleted, StatusFailed, StatusReversed:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether a transaction may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range transactionTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo moves the transaction to a new status and appends the change
+// to its history. The reason is kept as the failure reason when failing.
+func (t *Transaction) TransitionTo(to, reason string, at time.Time) (StatusChange, error) {
+	if !CanTransition(t.Status, to) {
+		return StatusChange{}, fmt.Errorf("%w: %q to %q", ErrIllegalTransition, t.Status, to)
+	}
+
+	change := StatusChange{From: t.Status, To: to, Reason: reason, At: at}
+	t.Status = to
+	t.UpdatedAt = at
+	if to == StatusFailed {
+		t.FailureReason = reason
+	}
+	t.StatusHistory = append(t.StatusHistory, change)
+	return change, nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestTransactionStatusTransitions tests the transaction state machine
+func TestTransactionStatusTransitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		from        string
+		to          string
+		expectError bool
+	}{
+		// ✅ Legal transitions
+		{"New transaction is pending", "", StatusPending, false},
+		{"Pending to processing", StatusPending, StatusProcessing, false},
+		{"Pending to failed", StatusPending, StatusFailed, false},
+		{"Processing to completed", StatusProcessing, StatusCompleted, false},
+		{"Processing to failed", StatusProcessing, StatusFailed, false},
+		{"Completed to reversed", StatusCompleted, StatusReversed, false},
+
+		// ❌ Illegal transitions
+		{"New transaction cannot start completed", "", StatusCompleted, true},
+		{"Pending cannot skip processing", StatusPending, StatusCompleted, true},
+		{"Completed cannot fail", StatusCompleted, StatusFailed, true},
+		{"Failed is final", StatusFailed, StatusProcessing, true},
+		{"Reversed is final", StatusReversed, StatusCompleted, true},
+		{"Pending cannot be reversed", StatusPending, StatusReversed, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			txn := Transaction{Status: tc.from}
+			_, err := txn.TransitionTo(tc.to, "", time.Now())
+
+			if tc.expectError {
+				if !errors.Is(err, ErrIllegalTransition) {
+					t.Fatalf("Expected illegal transition error, got %v", err)
+				}
+				if txn.Status != tc.from || len(txn.StatusHistory) != 0 {
+					t.Errorf("Rejected transition must not change the transaction, got status %q", txn.Status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if txn.Status != tc.to {
+				t.Errorf("Expected status %q, got %q", tc.to, txn.Status)
+			}
+		})
+	}
+}
+
+// TestTransactionStatusHistory tests that each transition is recorded with its reason
+func TestTransactionStatusHistory(t *testing.T) {
+	var txn Transaction
+	start := time.Now()
+
+	txn.TransitionTo(StatusPending, "", start)
+	txn.TransitionTo(StatusProcessing, "", start.Add(time.Second))
+	txn.TransitionTo(StatusFailed, "insufficient funds for withdrawal", start.Add(2*time.Second))
+
+	if len(txn.StatusHistory) != 3 {
+		t.Fatalf("Expected 3 status changes, got %d", len(txn.StatusHistory))
+	}
+	last := txn.StatusHistory[2]
+	if last.From != StatusProcessing || last.To != StatusFailed || last.Reason != "insufficient funds for withdrawal" {
+		t.Errorf("Unexpected last status change: %+v", last)
+	}
+	if txn.FailureReason != last.Reason {
+		t.Errorf("Expected failure reason %q, got %q", last.Reason, txn.FailureReason)
+	}
+	if !txn.UpdatedAt.Equal(last.At) {
+		t.Errorf("Expected UpdatedAt to match the last transition, got %s", txn.UpdatedAt)
+	}
+}
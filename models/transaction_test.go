@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 // TestTransactionValidation tests the transaction validation logic
@@ -19,8 +21,7 @@ func TestTransactionValidation(t *testing.T) {
 			transaction: Transaction{
 				Type:          "deposit",
 				AccountNumber: "12345",
-				Amount:        100.00,
-				Currency:      "USD",
+				Amount:        NewMoney(10000, "USD"),
 				CreatedAt:     time.Now(),
 			},
 			expectError: false,
@@ -29,9 +30,8 @@ func TestTransactionValidation(t *testing.T) {
 		{
 			name: "Invalid deposit (missing account number)",
 			transaction: Transaction{
-				Type:     "deposit",
-				Amount:   50.00,
-				Currency: "USD",
+				Type:   "deposit",
+				Amount: NewMoney(5000, "USD"),
 			},
 			expectError: true,
 		},
@@ -41,8 +41,7 @@ func TestTransactionValidation(t *testing.T) {
 			transaction: Transaction{
 				Type:          "withdrawal",
 				AccountNumber: "67890",
-				Amount:        200.00,
-				Currency:      "EUR",
+				Amount:        NewMoney(20000, "EUR"),
 				CreatedAt:     time.Now(),
 			},
 			expectError: false,
@@ -53,8 +52,7 @@ func TestTransactionValidation(t *testing.T) {
 			transaction: Transaction{
 				Type:          "withdrawal",
 				AccountNumber: "67890",
-				Amount:        -50.00,
-				Currency:      "USD",
+				Amount:        NewMoney(-5000, "USD"),
 			},
 			expectError: true,
 		},
@@ -65,8 +63,7 @@ func TestTransactionValidation(t *testing.T) {
 				Type:               "transfer",
 				SourceAccount:      "12345",
 				DestinationAccount: "67890",
-				Amount:             300.00,
-				Currency:           "GBP",
+				Amount:             NewMoney(30000, "GBP"),
 				CreatedAt:          time.Now(),
 			},
 			expectError: false,
@@ -78,8 +75,7 @@ func TestTransactionValidation(t *testing.T) {
 				Type:               "transfer",
 				SourceAccount:      "11111",
 				DestinationAccount: "11111",
-				Amount:             100.00,
-				Currency:           "USD",
+				Amount:             NewMoney(10000, "USD"),
 			},
 			expectError: true,
 		},
@@ -89,8 +85,7 @@ func TestTransactionValidation(t *testing.T) {
 			transaction: Transaction{
 				Type:          "exchange",
 				AccountNumber: "12345",
-				Amount:        50.00,
-				Currency:      "USD",
+				Amount:        NewMoney(5000, "USD"),
 			},
 			expectError: true,
 		},
@@ -113,3 +108,58 @@ func TestTransactionValidation(t *testing.T) {
 		})
 	}
 }
+
+// TestTransactionBSON tests decoding stored transactions, including ones
+// written with a double amount and a separate currency
+func TestTransactionBSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		document       bson.M
+		expectedAmount Money
+		expectErr      bool
+	}{
+		// ✅ Current documents
+		{"Money amount", bson.M{"type": "deposit", "amount": bson.M{"minor_units": int64(1050), "currency": "USD"}}, NewMoney(1050, "USD"), false},
+		// ✅ Legacy documents
+		{"Legacy amount", bson.M{"type": "deposit", "amount": 10.5, "currency": "USD"}, NewMoney(1050, "USD"), false},
+		{"Legacy inexact amount", bson.M{"type": "transfer", "amount": 0.1 + 0.2, "currency": "EUR"}, NewMoney(30, "EUR"), false},
+		{"Legacy yen amount", bson.M{"type": "deposit", "amount": 1000.0, "currency": "JPY"}, NewMoney(1000, "JPY"), false},
+		// ❌ Legacy amount without a known currency
+		{"Legacy amount without currency", bson.M{"type": "deposit", "amount": 10.5}, Money{}, true},
+		// ❌ Amount of an unexpected type
+		{"String amount", bson.M{"type": "deposit", "amount": "10.50", "currency": "USD"}, Money{}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			data, err := bson.Marshal(tc.document)
+			if err != nil {
+				t.Fatalf("Failed to marshal document: %v", err)
+			}
+
+			var transaction Transaction
+			err = bson.Unmarshal(data, &transaction)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Expected error=%v, got %v", tc.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if transaction.Amount != tc.expectedAmount || transaction.Type != tc.document["type"] {
+				t.Errorf("Expected a %s of %s, got a %s of %s", tc.document["type"], tc.expectedAmount, transaction.Type, transaction.Amount)
+			}
+		})
+	}
+
+	// Stored transactions round-trip
+	stored := Transaction{Type: "transfer", SourceAccount: "1111111111", Amount: NewMoney(250, "GBP")}
+	data, err := bson.Marshal(stored)
+	if err != nil {
+		t.Fatalf("Failed to marshal transaction: %v", err)
+	}
+	var decoded Transaction
+	if err := bson.Unmarshal(data, &decoded); err != nil || decoded.Amount != stored.Amount || decoded.SourceAccount != stored.SourceAccount {
+		t.Errorf("Expected %+v, got %+v (%v)", stored, decoded, err)
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// MinPasswordLength is the shortest password accepted when one is set
+const MinPasswordLength = 8
+
 // User represents a system user.
 type User struct {
 	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
@@ -15,6 +18,7 @@ type User struct {
 	Email     string    `gorm:"unique;not null" json:"email"`
 	Phone     string    `gorm:"not null" json:"phone"`
 	Password  string    `gorm:"not null" json:"-"`
+	Role      string    `gorm:"type:text;not null;default:'customer'" json:"role"`
 	CreatedAt time.Time `gorm:"not null;default:current_timestamp"`
 	UpdatedAt time.Time `gorm:"not null;default:current_timestamp"`
 }
@@ -22,6 +26,9 @@ type User struct {
 // BeforeCreate hashes the password and generates a UUID before inserting the user
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	u.ID = uuid.New()
+	if u.Role == "" {
+		u.Role = RoleCustomer
+	}
 	return nil
 }
 
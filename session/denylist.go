@@ -0,0 +1,37 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Denylist records revoked access tokens by their jti claim until they would
+// have expired anyway
+type Denylist struct {
+	Redis *redis.Client
+}
+
+// NewDenylist creates a token denylist
+func NewDenylist(client *redis.Client) *Denylist {
+	return &Denylist{Redis: client}
+}
+
+func revokedTokenKey(jti string) string { return "revoked-token:" + jti }
+
+// Revoke denies a token until expiresAt. Tokens that have already expired
+// are not recorded.
+func (d *Denylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return d.Redis.Set(ctx, revokedTokenKey(jti), 1, ttl).Err()
+}
+
+// IsRevoked reports whether a token has been revoked
+func (d *Denylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := d.Redis.Exists(ctx, revokedTokenKey(jti)).Result()
+	return n > 0, err
+}
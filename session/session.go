@@ -0,0 +1,311 @@
+// Package session keeps one login session per device in Redis. A session is
+// resumed with a rotating refresh token; presenting a refresh token that has
+// already been rotated away revokes the session, since it means the token
+// was copied.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// DefaultRefreshTTL is how long a session lives without being refreshed
+const DefaultRefreshTTL = 30 * 24 * time.Hour
+
+// touchInterval limits how often LastSeenAt is written back to Redis
+const touchInterval = time.Minute
+
+// Session errors
+var (
+	ErrNotFound            = errors.New("session not found")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused; session revoked")
+)
+
+// Session is one signed-in device
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	MFA        bool      `json:"mfa"` // Signed in with a second factor
+}
+
+// record is a session as stored, with the hash of its current refresh token
+type record struct {
+	Session
+	RefreshHash string `json:"refresh_hash"`
+}
+
+// Store keeps sessions in Redis. Each session lives under its own key and
+// the user's session IDs are kept in a set so they can be listed.
+type Store struct {
+	Redis      *redis.Client
+	RefreshTTL time.Duration
+}
+
+// NewStore creates a session store. A zero refreshTTL uses DefaultRefreshTTL.
+func NewStore(client *redis.Client, refreshTTL time.Duration) *Store {
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTTL
+	}
+	return &Store{Redis: client, RefreshTTL: refreshTTL}
+}
+
+func sessionKey(id string) string          { return "session:" + id }
+func userSessionsKey(userID string) string { return "user-sessions:" + userID }
+
+// Create starts a session for a user and returns it with its first refresh
+// token. mfa records whether the user passed a second factor.
+func (s *Store) Create(ctx context.Context, userID, userAgent, ip string, mfa bool) (*Session, string, error) {
+	now := time.Now().UTC()
+	rec := record{Session: Session{
+		ID:         uuid.NewString(),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		MFA:        mfa,
+	}}
+
+	token, err := newRefreshToken(rec.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	rec.RefreshHash = hashToken(token)
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, "", err
+	}
+	_, err = s.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(rec.ID), data, s.RefreshTTL)
+		pipe.SAdd(ctx, userSessionsKey(userID), rec.ID)
+		pipe.Expire(ctx, userSessionsKey(userID), s.RefreshTTL)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &rec.Session, token, nil
+}
+
+// Get returns a live session.
+func (s *Store) Get(ctx context.Context, id string) (*Session, error) {
+	rec, err := s.load(ctx, s.Redis, id)
+	if err != nil {
+		return nil, err
+	}
+	return &rec.Session, nil
+}
+
+// Touch records that a session was just used. Writes are skipped if it was
+// seen within the last minute.
+func (s *Store) Touch(ctx context.Context, session *Session, ip string) error {
+	if time.Since(session.LastSeenAt) < touchInterval {
+		return nil
+	}
+	return s.Redis.Watch(ctx, func(tx *redis.Tx) error {
+		rec, err := s.load(ctx, tx, session.ID)
+		if err != nil {
+			return err
+		}
+		rec.LastSeenAt = time.Now().UTC()
+		if ip != "" {
+			rec.IP = ip
+		}
+		return s.save(ctx, tx, rec, redis.KeepTTL)
+	}, sessionKey(session.ID))
+}
+
+// Refresh exchanges a refresh token for a new one and extends the session.
+// A token that has already been exchanged revokes the session.
+func (s *Store) Refresh(ctx context.Context, token, ip string) (*Session, string, error) {
+	id, ok := sessionIDFromToken(token)
+	if !ok {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	var session *Session
+	var next string
+	reusedBy := "" // Owner of the session when a stale token is presented
+	err := s.Redis.Watch(ctx, func(tx *redis.Tx) error {
+		rec, err := s.load(ctx, tx, id)
+		if errors.Is(err, ErrNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(rec.RefreshHash), []byte(hashToken(token))) != 1 {
+			reusedBy = rec.UserID
+			return nil
+		}
+
+		if next, err = newRefreshToken(id); err != nil {
+			return err
+		}
+		rec.RefreshHash = hashToken(next)
+		rec.LastSeenAt = time.Now().UTC()
+		if ip != "" {
+			rec.IP = ip
+		}
+		if err := s.save(ctx, tx, rec, s.RefreshTTL); err != nil {
+			return err
+		}
+		session = &rec.Session
+		return nil
+	}, sessionKey(id))
+
+	if errors.Is(err, redis.TxFailedErr) {
+		// Someone else refreshed this session at the same moment
+		return nil, "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if reusedBy != "" {
+		if err := s.Revoke(ctx, reusedBy, id); err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, "", err
+		}
+		return nil, "", ErrRefreshTokenReused
+	}
+	return session, next, nil
+}
+
+// List returns a user's live sessions, most recently used first.
+func (s *Store) List(ctx context.Context, userID string) ([]Session, error) {
+	ids, err := s.Redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []Session{}
+	var expired []interface{}
+	for _, id := range ids {
+		rec, err := s.load(ctx, s.Redis, id)
+		if errors.Is(err, ErrNotFound) {
+			expired = append(expired, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, rec.Session)
+	}
+	if len(expired) > 0 {
+		s.Redis.SRem(ctx, userSessionsKey(userID), expired...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions, nil
+}
+
+// Revoke ends one of a user's sessions.
+func (s *Store) Revoke(ctx context.Context, userID, id string) error {
+	rec, err := s.load(ctx, s.Redis, id)
+	if err != nil {
+		return err
+	}
+	if rec.UserID != userID {
+		return ErrNotFound
+	}
+
+	_, err = s.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionKey(id))
+		pipe.SRem(ctx, userSessionsKey(userID), id)
+		return nil
+	})
+	return err
+}
+
+// RevokeAll ends every session of a user and returns how many there were.
+func (s *Store) RevokeAll(ctx context.Context, userID string) (int, error) {
+	ids, err := s.Redis.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	keys := []string{userSessionsKey(userID)}
+	for _, id := range ids {
+		keys = append(keys, sessionKey(id))
+	}
+	if err := s.Redis.Del(ctx, keys...).Err(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+func (s *Store) load(ctx context.Context, client redis.Cmdable, id string) (*record, error) {
+	data, err := client.Get(ctx, sessionKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *Store) save(ctx context.Context, tx *redis.Tx, rec *record, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionKey(rec.ID), data, ttl)
+		if ttl != redis.KeepTTL {
+			pipe.Expire(ctx, userSessionsKey(rec.UserID), ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// newRefreshToken makes a refresh token of the form "<session id>.<secret>"
+func newRefreshToken(sessionID string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return sessionID + "." + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// sessionIDFromToken returns the session a refresh token belongs to
+func sessionIDFromToken(token string) (string, bool) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || secret == "" {
+		return "", false
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+// hashToken is what is stored in place of a refresh token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,160 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// TestSessionIDFromToken tests which refresh tokens are well formed
+func TestSessionIDFromToken(t *testing.T) {
+	id := uuid.NewString()
+	token, err := newRefreshToken(id)
+	if err != nil {
+		t.Fatalf("Failed to create refresh token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		expectedOK bool
+	}{
+		// ✅ Freshly issued token
+		{"Issued token", token, true},
+		// ❌ No secret
+		{"Missing secret", id + ".", false},
+		// ❌ No session ID
+		{"Missing session", "abc", false},
+		// ❌ Session ID is not a UUID
+		{"Bad session ID", "user-1.secret", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			got, ok := sessionIDFromToken(tc.token)
+			if ok != tc.expectedOK {
+				t.Fatalf("Expected ok=%v, got %v", tc.expectedOK, ok)
+			}
+			if ok && got != id {
+				t.Errorf("Expected session %s, got %s", id, got)
+			}
+		})
+	}
+}
+
+// TestRefreshRotation tests refresh token rotation and reuse detection.
+// It needs a real Redis server, so it only runs when TEST_REDIS_ADDR is set.
+func TestRefreshRotation(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+
+	ctx := context.Background()
+	store := NewStore(redis.NewClient(&redis.Options{Addr: addr}), time.Hour)
+	userID := uuid.NewString()
+
+	laptop, first, err := store.Create(ctx, userID, "laptop", "10.0.0.1", false)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	phone, _, err := store.Create(ctx, userID, "phone", "10.0.0.2", false)
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	// Logging in on a second device keeps the first
+	if sessions, _ := store.List(ctx, userID); len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+
+	_, second, err := store.Refresh(ctx, first, "10.0.0.3")
+	if err != nil {
+		t.Fatalf("Expected refresh to succeed, got %v", err)
+	}
+	if second == first {
+		t.Fatal("Expected the refresh token to rotate")
+	}
+
+	// Replaying the rotated token revokes the laptop session, but not the phone
+	if _, _, err := store.Refresh(ctx, first, ""); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected reuse to be detected, got %v", err)
+	}
+	if _, _, err := store.Refresh(ctx, second, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected the revoked session to refuse refreshes, got %v", err)
+	}
+	if _, err := store.Get(ctx, laptop.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the laptop session to be revoked, got %v", err)
+	}
+	if _, err := store.Get(ctx, phone.ID); err != nil {
+		t.Errorf("Expected the phone session to survive, got %v", err)
+	}
+
+	// Sessions can only be revoked by their owner
+	if err := store.Revoke(ctx, uuid.NewString(), phone.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected another user's revoke to fail, got %v", err)
+	}
+	if err := store.Revoke(ctx, userID, phone.ID); err != nil {
+		t.Errorf("Expected revoke to succeed, got %v", err)
+	}
+}
+
+// TestDenylist tests that revoked tokens stay denied until they expire.
+// It needs a real Redis server, so it only runs when TEST_REDIS_ADDR is set.
+func TestDenylist(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+
+	ctx := context.Background()
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	denylist := NewDenylist(client)
+
+	tests := []struct {
+		name            string
+		expiresAt       time.Time
+		expectedRevoked bool
+	}{
+		// ✅ Denied for the rest of its lifetime
+		{"Live token", time.Now().Add(time.Minute), true},
+		// ❌ Already expired tokens are not recorded
+		{"Expired token", time.Now().Add(-time.Minute), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			jti := uuid.NewString()
+			if err := denylist.Revoke(ctx, jti, tc.expiresAt); err != nil {
+				t.Fatalf("Failed to revoke token: %v", err)
+			}
+			revoked, err := denylist.IsRevoked(ctx, jti)
+			if err != nil || revoked != tc.expectedRevoked {
+				t.Errorf("Expected revoked=%v, got %v (%v)", tc.expectedRevoked, revoked, err)
+			}
+			if ttl := client.TTL(ctx, revokedTokenKey(jti)).Val(); revoked && ttl > time.Minute {
+				t.Errorf("Expected the entry to expire with the token, got TTL %s", ttl)
+			}
+		})
+	}
+
+	// Revoking all of a user's sessions ends every device
+	store := NewStore(client, time.Hour)
+	userID := uuid.NewString()
+	store.Create(ctx, userID, "laptop", "", false)
+	store.Create(ctx, userID, "phone", "", false)
+	if revoked, err := store.RevokeAll(ctx, userID); err != nil || revoked != 2 {
+		t.Errorf("Expected 2 sessions revoked, got %d (%v)", revoked, err)
+	}
+	if sessions, _ := store.List(ctx, userID); len(sessions) != 0 {
+		t.Errorf("Expected no sessions left, got %d", len(sessions))
+	}
+}
@@ -0,0 +1,169 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+)
+
+// camt053Namespace identifies the ISO 20022 BankToCustomerStatement version
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// ISO 20022 elements used by the statement. Field order follows the schema.
+type camtDocument struct {
+	XMLName xml.Name      `xml:"Document"`
+	Xmlns   string        `xml:"xmlns,attr"`
+	Stmt    camtStatement `xml:"BkToCstmrStmt"`
+}
+
+type camtStatement struct {
+	GrpHdr struct {
+		MsgId   string `xml:"MsgId"`
+		CreDtTm string `xml:"CreDtTm"`
+	} `xml:"GrpHdr"`
+	Stmt struct {
+		Id      string `xml:"Id"`
+		CreDtTm string `xml:"CreDtTm"`
+		FrToDt  struct {
+			FrDtTm string `xml:"FrDtTm"`
+			ToDtTm string `xml:"ToDtTm"`
+		} `xml:"FrToDt"`
+		Acct struct {
+			Id   camtAccountID `xml:"Id"`
+			Ccy  string        `xml:"Ccy"`
+			Ownr struct {
+				Nm string `xml:"Nm"`
+			} `xml:"Ownr"`
+		} `xml:"Acct"`
+		Bal       []camtBalance `xml:"Bal"`
+		TxsSummry struct {
+			NbOfNtries int `xml:"TtlNtries>NbOfNtries"`
+		} `xml:"TxsSummry"`
+		Ntry []camtEntry `xml:"Ntry"`
+	} `xml:"Stmt"`
+}
+
+type camtAccountID struct {
+	Othr string `xml:"Othr>Id"`
+}
+
+type camtAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	DtTm      string     `xml:"Dt>DtTm"`
+}
+
+type camtEntry struct {
+	Amt         camtAmount `xml:"Amt"`
+	CdtDbtInd   string     `xml:"CdtDbtInd"`
+	RvslInd     bool       `xml:"RvslInd,omitempty"`
+	Sts         string     `xml:"Sts"`
+	BookgDt     string     `xml:"BookgDt>DtTm"`
+	ValDt       string     `xml:"ValDt>DtTm"`
+	AcctSvcrRef string     `xml:"AcctSvcrRef"`
+	BkTxCd      string     `xml:"BkTxCd>Prtry>Cd"`
+	TxDtls      struct {
+		AcctSvcrRef string       `xml:"Refs>AcctSvcrRef"`
+		RltdPties   *camtParties `xml:"RltdPties,omitempty"`
+		AddtlTxInf  string       `xml:"AddtlTxInf,omitempty"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtParties struct {
+	DbtrAcct *camtCounterpartyAccount `xml:"DbtrAcct,omitempty"`
+	CdtrAcct *camtCounterpartyAccount `xml:"CdtrAcct,omitempty"`
+}
+
+type camtCounterpartyAccount struct {
+	Id camtAccountID `xml:"Id"`
+}
+
+// WriteCamt053 renders the statement as an ISO 20022 camt.053.001.02
+// BankToCustomerStatement with opening (OPBD) and closing (CLBD) booked
+// balances and one booked entry per posting.
+func WriteCamt053(w io.Writer, s *Statement) error {
+	doc := camtDocument{Xmlns: camt053Namespace}
+	stmt := &doc.Stmt
+
+	id := s.Account.AccountNumber + "-" + s.From.UTC().Format("20060102") + "-" + s.To.UTC().Format("20060102")
+	stmt.GrpHdr.MsgId = id
+	stmt.GrpHdr.CreDtTm = isoDateTime(s.GeneratedAt)
+
+	stmt.Stmt.Id = id
+	stmt.Stmt.CreDtTm = isoDateTime(s.GeneratedAt)
+	stmt.Stmt.FrToDt.FrDtTm = isoDateTime(s.From)
+	stmt.Stmt.FrToDt.ToDtTm = isoDateTime(s.To)
+	stmt.Stmt.Acct.Id.Othr = s.Account.AccountNumber
+	stmt.Stmt.Acct.Ccy = s.Account.Currency
+	stmt.Stmt.Acct.Ownr.Nm = s.Account.OwnerName
+	stmt.Stmt.Bal = []camtBalance{
+		camtBalanceOf("OPBD", s.OpeningBalance, s.From),
+		camtBalanceOf("CLBD", s.ClosingBalance, s.To),
+	}
+	stmt.Stmt.TxsSummry.NbOfNtries = len(s.Entries)
+
+	for _, e := range s.Entries {
+		amount, indicator := camtAmountOf(e.Amount)
+		entry := camtEntry{
+			Amt:         amount,
+			CdtDbtInd:   indicator,
+			RvslInd:     e.Type == "reversal",
+			Sts:         "BOOK",
+			BookgDt:     isoDateTime(e.Date),
+			ValDt:       isoDateTime(e.Date),
+			AcctSvcrRef: e.JournalEntryID,
+			BkTxCd:      e.Type,
+		}
+		entry.TxDtls.AcctSvcrRef = e.JournalEntryID
+		entry.TxDtls.AddtlTxInf = e.Description
+		if e.Counterparty != "" {
+			account := &camtCounterpartyAccount{Id: camtAccountID{Othr: e.Counterparty}}
+			if indicator == "CRDT" {
+				entry.TxDtls.RltdPties = &camtParties{DbtrAcct: account}
+			} else {
+				entry.TxDtls.RltdPties = &camtParties{CdtrAcct: account}
+			}
+		}
+		stmt.Stmt.Ntry = append(stmt.Stmt.Ntry, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// camtAmountOf splits a signed amount into the unsigned amount and the
+// credit/debit indicator used by ISO 20022
+func camtAmountOf(m models.Money) (camtAmount, string) {
+	indicator := "CRDT"
+	if m.IsNegative() {
+		m = m.Neg()
+		indicator = "DBIT"
+	}
+	return camtAmount{Ccy: m.Currency, Value: m.Decimal()}, indicator
+}
+
+func camtBalanceOf(code string, balance models.Money, at time.Time) camtBalance {
+	amount, indicator := camtAmountOf(balance)
+	return camtBalance{Code: code, Amt: amount, CdtDbtInd: indicator, DtTm: isoDateTime(at)}
+}
+
+// isoDateTime formats a time as an ISO 20022 ISODateTime in UTC
+func isoDateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
@@ -0,0 +1,45 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteCSV renders the statement as CSV: an opening balance row, one row per
+// entry and a closing balance row.
+func WriteCSV(w io.Writer, s *Statement) error {
+	out := csv.NewWriter(w)
+
+	rows := [][]string{
+		{"date", "entry_id", "type", "description", "counterparty", "amount", "balance", "currency"},
+		{s.From.Format(time.RFC3339), "", "", "Opening balance", "", "", s.OpeningBalance.Decimal(), s.Account.Currency},
+	}
+	for _, e := range s.Entries {
+		rows = append(rows, []string{
+			e.Date.Format(time.RFC3339),
+			e.JournalEntryID,
+			e.Type,
+			safeCell(e.Description),
+			safeCell(e.Counterparty),
+			e.Amount.Decimal(),
+			e.Balance.Decimal(),
+			s.Account.Currency,
+		})
+	}
+	rows = append(rows, []string{s.To.Format(time.RFC3339), "", "", "Closing balance", "", "", s.ClosingBalance.Decimal(), s.Account.Currency})
+
+	if err := out.WriteAll(rows); err != nil {
+		return err
+	}
+	return out.Error()
+}
+
+// safeCell stops free text from being read as a formula by spreadsheets
+func safeCell(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
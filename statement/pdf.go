@@ -0,0 +1,137 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page layout in points (A4, monospaced text)
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	pageMargin   = 50
+	fontSize     = 9
+	lineHeight   = 12
+	linesPerPage = (pageHeight - 2*pageMargin) / lineHeight
+)
+
+// WritePDF renders the statement as a plain-text PDF document. It writes the
+// PDF structure directly, using only the standard Courier font, so no
+// external library is needed.
+func WritePDF(w io.Writer, s *Statement) error {
+	lines := pdfLines(s)
+
+	var pages [][]string
+	for len(lines) > 0 {
+		n := linesPerPage - 2 // Leave room for the page footer
+		if n > len(lines) {
+			n = len(lines)
+		}
+		pages = append(pages, lines[:n])
+		lines = lines[n:]
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+
+	// Objects 4 and 5 are the first page and its content, and so on
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>")
+
+	for i, page := range pages {
+		content := pageContent(page, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfLines lays the statement out as lines of monospaced text
+func pdfLines(s *Statement) []string {
+	row := func(date, description, amount, balance string) string {
+		return fmt.Sprintf("%-10s  %-40s %14s %14s", date, truncate(description, 40), amount, balance)
+	}
+
+	lines := []string{
+		"ACCOUNT STATEMENT",
+		"",
+		fmt.Sprintf("Account:   %s (%s, %s)", s.Account.AccountNumber, s.Account.AccountType, s.Account.Currency),
+		fmt.Sprintf("Holder:    %s", s.Account.OwnerName),
+		fmt.Sprintf("Period:    %s to %s", s.From.Format("2006-01-02 15:04 MST"), s.To.Format("2006-01-02 15:04 MST")),
+		fmt.Sprintf("Generated: %s", s.GeneratedAt.Format("2006-01-02 15:04 MST")),
+		"",
+		row("Date", "Description", "Amount", "Balance"),
+		strings.Repeat("-", 82),
+		row(s.From.Format("2006-01-02"), "Opening balance", "", s.OpeningBalance.Decimal()),
+	}
+	for _, e := range s.Entries {
+		lines = append(lines, row(e.Date.Format("2006-01-02"), e.Description, e.Amount.Decimal(), e.Balance.Decimal()))
+	}
+	lines = append(lines,
+		row(s.To.Format("2006-01-02"), "Closing balance", "", s.ClosingBalance.Decimal()),
+		strings.Repeat("-", 82),
+		fmt.Sprintf("%d entries. Amounts in %s; debits are negative.", len(s.Entries), s.Account.Currency),
+	)
+	return lines
+}
+
+// pageContent draws lines of text from the top of the page and a footer
+func pageContent(lines []string, footer string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, lineHeight, pageMargin, pageHeight-pageMargin)
+	for _, line := range lines {
+		fmt.Fprintf(&b, "(%s) Tj T*\n", pdfEscape(line))
+	}
+	fmt.Fprintf(&b, "ET\nBT\n/F1 %d Tf\n%d %d Td\n(%s) Tj\nET", fontSize, pageMargin, pageMargin/2, pdfEscape(footer))
+	return b.String()
+}
+
+// pdfEscape makes text safe inside a PDF string literal. Characters outside
+// printable ASCII are not in the standard font encoding and become '?'.
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteRune('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// truncate shortens text to at most n characters
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n-3]) + "..."
+}
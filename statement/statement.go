@@ -0,0 +1,132 @@
+// Package statement builds account statements from the ledger and renders
+// them as CSV, PDF or ISO 20022 camt.053 XML.
+package statement
+
+import (
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/ledger"
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/gorm"
+)
+
+// Statement lists the postings to an account over a period, between its
+// opening and closing balances.
+type Statement struct {
+	Account        models.Account
+	From           time.Time // Inclusive
+	To             time.Time // Exclusive
+	OpeningBalance models.Money
+	ClosingBalance models.Money
+	Entries        []Entry
+	GeneratedAt    time.Time
+}
+
+// Entry is one posting on a statement. Amount is positive for credits and
+// negative for debits; Balance is the running balance after the entry.
+type Entry struct {
+	Date           time.Time
+	JournalEntryID string
+	Type           string
+	Description    string
+	Counterparty   string
+	Amount         models.Money
+	Balance        models.Money
+}
+
+// postingRow is a posting joined with its journal entry and, where recorded,
+// its ledger transaction
+type postingRow struct {
+	JournalEntryID     string
+	Direction          string
+	Amount             int64
+	CreatedAt          time.Time
+	Type               string
+	Description        string
+	SourceAccount      string
+	DestinationAccount string
+}
+
+// Build produces the statement of an account for postings made in [from, to).
+func Build(db *gorm.DB, accountNumber string, from, to time.Time) (*Statement, error) {
+	var account models.Account
+	if err := db.Unscoped().Where("account_number = ?", accountNumber).First(&account).Error; err != nil {
+		return nil, err
+	}
+
+	opening, err := ledger.BalanceAt(db, accountNumber, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []postingRow
+	err = db.Table("postings").
+		Select(`postings.journal_entry_id, postings.direction, postings.amount, postings.created_at,
+			journal_entries.type, journal_entries.description,
+			COALESCE(ledger_transactions.source_account, '') AS source_account,
+			COALESCE(ledger_transactions.destination_account, '') AS destination_account`).
+		Joins("JOIN journal_entries ON journal_entries.id = postings.journal_entry_id").
+		Joins("LEFT JOIN ledger_transactions ON ledger_transactions.journal_entry_id = postings.journal_entry_id").
+		Where("postings.account_number = ? AND postings.created_at >= ? AND postings.created_at < ?",
+			accountNumber, from.UTC(), to.UTC()).
+		Order("postings.created_at, postings.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Statement{
+		Account:        account,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		GeneratedAt:    time.Now().UTC(),
+	}
+
+	balance := opening
+	for _, row := range rows {
+		amount := models.NewMoney(row.Amount, account.Currency)
+		counterparty := row.SourceAccount
+		if row.Direction == models.Debit {
+			amount = amount.Neg()
+			counterparty = row.DestinationAccount
+		}
+		if balance, err = balance.Add(amount); err != nil {
+			return nil, err
+		}
+
+		s.Entries = append(s.Entries, Entry{
+			Date:           row.CreatedAt.UTC(),
+			JournalEntryID: row.JournalEntryID,
+			Type:           row.Type,
+			Description:    describe(row, counterparty),
+			Counterparty:   counterparty,
+			Amount:         amount,
+			Balance:        balance,
+		})
+	}
+	s.ClosingBalance = balance
+
+	return s, nil
+}
+
+// describe gives a short human-readable description of a posting
+func describe(row postingRow, counterparty string) string {
+	switch row.Type {
+	case "deposit":
+		return "Deposit"
+	case "withdrawal":
+		return "Withdrawal"
+	case "transfer":
+		if row.Direction == models.Debit {
+			return "Transfer to " + counterparty
+		}
+		return "Transfer from " + counterparty
+	case "reversal":
+		if row.Description != "" {
+			return "Reversal: " + row.Description
+		}
+		return "Reversal"
+	}
+	return row.Type
+}
@@ -0,0 +1,150 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/ledger"
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupStatement posts a deposit before the period and a transfer and a
+// withdrawal inside it, and returns the statement of the first account
+func setupStatement(t *testing.T) *Statement {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Account{}, &models.JournalEntry{}, &models.Posting{}, &models.LedgerTransaction{}, &models.BalanceSnapshot{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	for _, number := range []string{"1111111111", "2222222222"} {
+		account := models.Account{AccountNumber: number, OwnerName: "Alice (Test)", AccountType: "Savings", Currency: "USD", Status: models.AccountActive}
+		if err := db.Create(&account).Error; err != nil {
+			t.Fatalf("Failed to create account: %v", err)
+		}
+	}
+
+	post := func(entry *models.JournalEntry, at time.Time) {
+		for i := range entry.Postings {
+			entry.Postings[i].CreatedAt = at
+		}
+		if err := ledger.Post(db, entry); err != nil {
+			t.Fatalf("Failed to post %s: %v", entry.Type, err)
+		}
+	}
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 10, 0, 0, 0, time.UTC) }
+
+	post(models.NewDepositEntry("1111111111", models.NewMoney(10000, "USD"), ""), day(1))
+	transfer := models.NewTransferEntry("1111111111", "2222222222", models.NewMoney(2550, "USD"), "")
+	post(transfer, day(10))
+	db.Create(&models.LedgerTransaction{JournalEntryID: transfer.ID, Type: "transfer", SourceAccount: "1111111111", DestinationAccount: "2222222222", Amount: models.NewMoney(2550, "USD")})
+	post(models.NewWithdrawalEntry("1111111111", models.NewMoney(1000, "USD"), ""), day(20))
+	post(models.NewWithdrawalEntry("1111111111", models.NewMoney(500, "USD"), ""), time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC))
+
+	s, err := Build(db, "1111111111", day(5), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to build statement: %v", err)
+	}
+	return s
+}
+
+// TestBuild tests balances and running balances of a statement
+func TestBuild(t *testing.T) {
+	s := setupStatement(t)
+
+	if s.OpeningBalance != models.NewMoney(10000, "USD") {
+		t.Errorf("Expected opening balance 100.00 USD, got %s", s.OpeningBalance)
+	}
+	if s.ClosingBalance != models.NewMoney(6450, "USD") {
+		t.Errorf("Expected closing balance 64.50 USD, got %s", s.ClosingBalance)
+	}
+
+	expected := []struct {
+		description string
+		amount      int64
+		balance     int64
+	}{
+		{"Transfer to 2222222222", -2550, 7450},
+		{"Withdrawal", -1000, 6450},
+	}
+	if len(s.Entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(s.Entries))
+	}
+	for i, want := range expected {
+		got := s.Entries[i]
+		if got.Description != want.description || got.Amount.MinorUnits != want.amount || got.Balance.MinorUnits != want.balance {
+			t.Errorf("Entry %d: expected %+v, got %s %s %s", i, want, got.Description, got.Amount, got.Balance)
+		}
+	}
+}
+
+// TestRenderers tests that each format carries the statement balances
+func TestRenderers(t *testing.T) {
+	s := setupStatement(t)
+
+	tests := []struct {
+		name  string
+		write func(*bytes.Buffer, *Statement) error
+		check func(output []byte) error
+	}{
+		{"CSV", func(b *bytes.Buffer, s *Statement) error { return WriteCSV(b, s) }, func(output []byte) error {
+			rows, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+			if err != nil {
+				return err
+			}
+			if len(rows) != 5 { // Header, opening, two entries, closing
+				return fmt.Errorf("expected 5 rows, got %d", len(rows))
+			}
+			if rows[1][6] != "100.00" || rows[3][6] != "64.50" || rows[4][6] != "64.50" {
+				return fmt.Errorf("unexpected balances: %v", rows)
+			}
+			return nil
+		}},
+		{"PDF", func(b *bytes.Buffer, s *Statement) error { return WritePDF(b, s) }, func(output []byte) error {
+			text := string(output)
+			if !strings.HasPrefix(text, "%PDF-1.4") || !strings.HasSuffix(text, "%%EOF\n") {
+				return fmt.Errorf("not a PDF document")
+			}
+			if !strings.Contains(text, `Alice \(Test\)`) || !strings.Contains(text, "64.50") {
+				return fmt.Errorf("PDF is missing statement content")
+			}
+			return nil
+		}},
+		{"camt.053", func(b *bytes.Buffer, s *Statement) error { return WriteCamt053(b, s) }, func(output []byte) error {
+			var doc camtDocument
+			if err := xml.Unmarshal(output, &doc); err != nil {
+				return err
+			}
+			stmt := doc.Stmt.Stmt
+			if len(stmt.Bal) != 2 || stmt.Bal[0].Amt.Value != "100.00" || stmt.Bal[1].Amt.Value != "64.50" {
+				return fmt.Errorf("unexpected balances: %+v", stmt.Bal)
+			}
+			if len(stmt.Ntry) != 2 || stmt.Ntry[0].CdtDbtInd != "DBIT" || stmt.Ntry[0].Amt.Value != "25.50" {
+				return fmt.Errorf("unexpected entries: %+v", stmt.Ntry)
+			}
+			return nil
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			var buf bytes.Buffer
+			if err := tc.write(&buf, s); err != nil {
+				t.Fatalf("Failed to render: %v", err)
+			}
+			if err := tc.check(buf.Bytes()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -38,5 +39,27 @@ func InitMongo() *mongo.Database {
 
 	MongoDB = client.Database(os.Getenv("DB_NAME")) // Use correct DB name
 	log.Println("Connected to MongoDB successfully")
+
+	if err := ensureIndexes(ctx, MongoDB); err != nil {
+		log.Fatalf("Failed to create MongoDB indexes: %v", err)
+	}
 	return MongoDB
 }
+
+// ensureIndexes creates the indexes behind transaction history queries. Each
+// account field is paired with the (created_at, _id) sort key used for
+// cursor pagination.
+func ensureIndexes(ctx context.Context, db *mongo.Database) error {
+	byAccount := func(field string) mongo.IndexModel {
+		return mongo.IndexModel{Keys: bson.D{{Key: field, Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}}
+	}
+
+	_, err := db.Collection("transactions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		byAccount("account_number"),
+		byAccount("source_account"),
+		byAccount("destination_account"),
+		{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "reference", Value: 1}}},
+	})
+	return err
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/ashil-poojary/banking-ledger-service/models"
 	"github.com/joho/godotenv"
@@ -34,11 +35,30 @@ func InitPostgres() *gorm.DB {
 
 	log.Println("Connected to PostgreSQL successfully")
 
+	// Convert legacy float balances to minor units before AutoMigrate alters the column type
+	if err := migrateMoneyColumns(db); err != nil {
+		log.Fatalf("Failed to migrate money columns: %v", err)
+	}
+
+	if err := dropLegacyTransactionsTable(db); err != nil {
+		log.Fatalf("Failed to drop legacy transactions table: %v", err)
+	}
+
 	// Auto-migrate models
 	err = db.AutoMigrate(
 		&models.User{},
+		&models.LoginAttempt{},
+		&models.MFAFactor{},
+		&models.RecoveryCode{},
 		&models.Account{},
-		&models.Transaction{})
+		&models.AccountStatusChange{},
+		&models.LedgerTransaction{},
+		&models.BalanceSnapshot{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.IdempotencyKey{},
+		&models.ProcessedMessage{},
+		&models.OutboxMessage{})
 	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
@@ -47,3 +67,97 @@ func InitPostgres() *gorm.DB {
 	PostgresDB = db
 	return db
 }
+
+// migrateMoneyColumns rewrites float64 amount columns from earlier schema
+// versions as integer minor units of each row's currency. It stops without
+// converting anything if a row holds a currency the ledger does not support.
+func migrateMoneyColumns(db *gorm.DB) error {
+	columns := map[string]string{
+		"accounts": "balance",
+		"postings": "amount",
+	}
+
+	// Check every legacy column before converting any of them
+	legacy := make(map[string]string, len(columns))
+	for table, column := range columns {
+		if !db.Migrator().HasTable(table) {
+			continue
+		}
+
+		var dataType string
+		err := db.Raw(`SELECT data_type FROM information_schema.columns WHERE table_name = ? AND column_name = ?`, table, column).
+			Scan(&dataType).Error
+		if err != nil {
+			return err
+		}
+		if dataType != "double precision" {
+			continue
+		}
+
+		var unsupported []string
+		err = db.Raw(fmt.Sprintf(`SELECT DISTINCT COALESCE(currency, '') FROM %s WHERE currency IS NULL OR currency NOT IN ?`, table), models.SupportedCurrencies()).
+			Scan(&unsupported).Error
+		if err != nil {
+			return err
+		}
+		if len(unsupported) > 0 {
+			return fmt.Errorf("cannot convert %s.%s to minor units: unsupported currencies %q", table, column, unsupported)
+		}
+		legacy[table] = column
+	}
+
+	for table, column := range legacy {
+		sql := fmt.Sprintf(
+			`ALTER TABLE %s ALTER COLUMN %s TYPE bigint USING round(%s * %s)`,
+			table, column, column, minorUnitScale(),
+		)
+		if err := db.Exec(sql).Error; err != nil {
+			return err
+		}
+		log.Printf("Converted %s.%s to minor units", table, column)
+	}
+
+	return nil
+}
+
+// minorUnitScale builds a SQL expression giving the number of minor units in
+// one major unit of each row's currency, from the supported currency table.
+func minorUnitScale() string {
+	var expr strings.Builder
+	expr.WriteString("CASE currency")
+	for _, currency := range models.SupportedCurrencies() {
+		exp, _ := models.CurrencyExponent(currency)
+		scale := 1
+		for i := 0; i < exp; i++ {
+			scale *= 10
+		}
+		fmt.Fprintf(&expr, " WHEN '%s' THEN %d", currency, scale)
+	}
+	expr.WriteString(" END")
+	return expr.String()
+}
+
+// dropLegacyTransactionsTable removes the transactions table that earlier
+// versions migrated from the MongoDB transaction model. Nothing ever wrote to
+// it; posted transactions now live in ledger_transactions. A table holding
+// rows is left alone.
+func dropLegacyTransactionsTable(db *gorm.DB) error {
+	if !db.Migrator().HasTable("transactions") {
+		return nil
+	}
+
+	var rows int64
+	if err := db.Table("transactions").Count(&rows).Error; err != nil {
+		return err
+	}
+	if rows > 0 {
+		log.Println("Legacy transactions table is not empty; leaving it in place")
+		return nil
+	}
+
+	if err := db.Migrator().DropTable("transactions"); err != nil {
+		return err
+	}
+	log.Println("Dropped legacy transactions table")
+	return nil
+}
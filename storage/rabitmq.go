@@ -41,6 +41,23 @@ func InitRabbitMQ() (*amqp.Connection, *amqp.Channel) {
 	return conn, ch
 }
 
+// OpenConfirmChannel opens an additional channel in publisher-confirm mode
+// and returns it with the stream of broker confirmations. Confirmations
+// arrive per channel, so each confirming publisher needs its own.
+func OpenConfirmChannel() (*amqp.Channel, <-chan amqp.Confirmation) {
+	ch, err := RabbitMQConn.Channel()
+	if err != nil {
+		log.Fatalf("Failed to open a RabbitMQ channel: %v", err)
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		log.Fatalf("Failed to enable publisher confirms: %v", err)
+	}
+
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	return ch, confirms
+}
+
 // CloseRabbitMQ properly closes the connection and channel
 func CloseRabbitMQ() {
 	if RabbitMQChannel != nil {
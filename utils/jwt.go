@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 )
 
 // ExtractUserID extracts the user ID from a Bearer token in the Authorization header
@@ -30,20 +31,13 @@ func ExtractUserID(r *http.Request) (string, error) {
 		return "", errors.New("bearer token missing")
 	}
 
-	// Get secret key from environment
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		secretKey = "default_secret" // Fallback for testing
-	}
-
 	// Parse the token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			log.Println("[ERROR] Unexpected signing method")
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(secretKey), nil
-	})
+	keys, err := Keys()
+	if err != nil {
+		log.Println("[ERROR] No token keys:", err)
+		return "", errors.New("invalid token")
+	}
+	token, err := keys.Parse(tokenString)
 
 	if err != nil || !token.Valid {
 		log.Println("[ERROR] Invalid token:", err)
@@ -68,49 +62,104 @@ func ExtractUserID(r *http.Request) (string, error) {
 	return userIDStr, nil
 }
 
-// parseJWT extracts the UserID from the token
+// ParseJWT extracts the UserID from the token
 func ParseJWT(tokenString string) (string, error) {
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		secretKey = "default_secret"
+	userID, _, err := ParseJWTClaims(tokenString)
+	return userID, err
+}
+
+// DefaultAccessTokenTTL is the lifetime of an access token unless
+// ACCESS_TOKEN_TTL says otherwise. Sessions outlive it through refresh tokens.
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+// AccessClaims are the claims carried by an access token
+type AccessClaims struct {
+	ID        string // jti; unique per token so it can be revoked on its own
+	UserID    string
+	Role      string // Empty for tokens issued before roles existed
+	SessionID string // Empty for tokens issued before sessions existed
+	MFA       bool   // The session was signed in with a second factor
+	ExpiresAt time.Time
+}
+
+// ParseJWTClaims extracts the UserID and role from the token. Tokens issued
+// before roles existed carry no role and yield an empty one.
+func ParseJWTClaims(tokenString string) (string, string, error) {
+	claims, err := ParseAccessToken(tokenString)
+	if err != nil {
+		return "", "", err
 	}
+	return claims.UserID, claims.Role, nil
+}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method")
-		}
-		return []byte(secretKey), nil
-	})
+// ParseAccessToken validates a token and returns its claims
+func ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	keys, err := Keys()
+	if err != nil {
+		return nil, err
+	}
 
+	token, err := keys.Parse(tokenString)
 	if err != nil || !token.Valid {
-		return "", fmt.Errorf("invalid token")
+		return nil, fmt.Errorf("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", fmt.Errorf("invalid token claims")
+		return nil, fmt.Errorf("invalid token claims")
 	}
 
 	userID, ok := claims["user_id"].(string)
 	if !ok {
-		return "", fmt.Errorf("user_id not found in token")
+		return nil, fmt.Errorf("user_id not found in token")
 	}
 
-	return userID, nil
+	parsed := &AccessClaims{UserID: userID}
+	parsed.Role, _ = claims["role"].(string)
+	parsed.ID, _ = claims["jti"].(string)
+	parsed.SessionID, _ = claims["sid"].(string)
+	if amr, ok := claims["amr"].([]interface{}); ok {
+		for _, method := range amr {
+			parsed.MFA = parsed.MFA || method == "otp"
+		}
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		parsed.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	return parsed, nil
 }
 
-// GenerateJWT creates a signed token// GenerateJWT creates a signed token with UserID
-func GenerateJWT(userID string) (string, error) {
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		secretKey = "default_secret"
+// GenerateJWT creates a short-lived access token for a user's session. mfa
+// records whether the session was signed in with a second factor.
+func GenerateJWT(userID, role, sessionID string, mfa bool) (string, error) {
+	keys, err := Keys()
+	if err != nil {
+		return "", err
 	}
 
+	amr := []string{"pwd"} // Authentication methods (RFC 8176)
+	if mfa {
+		amr = append(amr, "otp")
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
+		"jti":     uuid.NewString(),
 		"user_id": userID, // Store UserID instead of username
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+		"role":    role,
+		"sid":     sessionID,
+		"amr":     amr,
+		"iat":     now.Unix(),
+		"exp":     now.Add(AccessTokenTTL()).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secretKey))
+	return keys.Sign(claims)
+}
+
+// AccessTokenTTL returns the configured access token lifetime
+func AccessTokenTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return DefaultAccessTokenTTL
 }
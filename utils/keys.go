@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// defaultSecret is the HS256 secret used when JWT_SECRET is unset. It is
+// public, so it is only accepted in development mode.
+const defaultSecret = "default_secret"
+
+// ErrDefaultSecret is returned when tokens would be signed with the public
+// default secret outside development mode
+var ErrDefaultSecret = errors.New("JWT_SECRET is unset or the default; configure JWT_SIGNING_KEY_FILE or set DEV_MODE=true")
+
+// verificationKey is a key tokens may be checked against
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{} // Public key, or the secret for HS256
+}
+
+// KeyManager signs tokens with one key and verifies them against any of the
+// keys it knows by kid, so old keys keep working while a new one rolls out.
+type KeyManager struct {
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    interface{}
+	keys          map[string]verificationKey
+	jwks          []JWK
+}
+
+// JWK is the public half of a signing key, as published in the JWKS
+type JWK struct {
+	KID string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet is the body of /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKeyManager signs and verifies tokens with a shared secret (HS256)
+func NewHMACKeyManager(secret string) *KeyManager {
+	m := &KeyManager{
+		signingMethod: jwt.SigningMethodHS256,
+		signingKey:    []byte(secret),
+		keys:          map[string]verificationKey{},
+	}
+	m.keys[""] = verificationKey{method: jwt.SigningMethodHS256, key: []byte(secret)}
+	return m
+}
+
+// NewKeyManager signs tokens with a PEM encoded RSA (RS256) or Ed25519
+// (EdDSA) private key. Tokens signed by the extra PEM encoded public keys
+// are still accepted, so keys can be rotated without logging everyone out.
+func NewKeyManager(signingPEM []byte, verificationPEMs ...[]byte) (*KeyManager, error) {
+	private, err := parsePrivateKey(signingPEM)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+
+	m := &KeyManager{signingKey: private, keys: map[string]verificationKey{}}
+	public := private.(crypto.Signer).Public()
+	if m.signingKID, m.signingMethod, err = m.addPublicKey(public); err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+
+	for i, data := range verificationPEMs {
+		public, err := parsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %d: %w", i+1, err)
+		}
+		if _, _, err := m.addPublicKey(public); err != nil {
+			return nil, fmt.Errorf("verification key %d: %w", i+1, err)
+		}
+	}
+	return m, nil
+}
+
+// LoadKeyManager builds the key manager from the environment.
+// JWT_SIGNING_KEY_FILE names the private key to sign with and
+// JWT_VERIFICATION_KEY_FILES lists, comma separated, retired public keys
+// that are still accepted. Without a signing key, tokens fall back to HS256
+// with JWT_SECRET, which must not be the default outside DEV_MODE.
+func LoadKeyManager() (*KeyManager, error) {
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		signingPEM, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var verificationPEMs [][]byte
+		for _, path := range strings.Split(os.Getenv("JWT_VERIFICATION_KEY_FILES"), ",") {
+			if path = strings.TrimSpace(path); path == "" {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			verificationPEMs = append(verificationPEMs, data)
+		}
+		return NewKeyManager(signingPEM, verificationPEMs...)
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" || secret == defaultSecret {
+		if devMode, _ := strconv.ParseBool(os.Getenv("DEV_MODE")); !devMode {
+			return nil, ErrDefaultSecret
+		}
+		secret = defaultSecret
+	}
+	return NewHMACKeyManager(secret), nil
+}
+
+// Sign signs claims with the current signing key and sets its kid header
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(m.signingMethod, claims)
+	if m.signingKID != "" {
+		token.Header["kid"] = m.signingKID
+	}
+	return token.SignedString(m.signingKey)
+}
+
+// Parse verifies a token against the key named by its kid header. The
+// token's alg must match the key, so a public key is never used as an HMAC
+// secret.
+func (m *KeyManager) Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := m.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return key.key, nil
+	})
+}
+
+// JWKS returns the public keys tokens may be signed with. It is empty when
+// tokens are signed with a shared secret.
+func (m *KeyManager) JWKS() JWKSet {
+	return JWKSet{Keys: append([]JWK{}, m.jwks...)}
+}
+
+// addPublicKey registers a verification key under its RFC 7638 thumbprint
+func (m *KeyManager) addPublicKey(public crypto.PublicKey) (string, jwt.SigningMethod, error) {
+	var jwk JWK
+	var method jwt.SigningMethod
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < 2048 {
+			return "", nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		method = jwt.SigningMethodRS256
+		jwk = JWK{Kty: "RSA", N: b64(key.N.Bytes()), E: b64(big.NewInt(int64(key.E)).Bytes())}
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+		jwk = JWK{Kty: "OKP", Crv: "Ed25519", X: b64(key)}
+	default:
+		return "", nil, fmt.Errorf("unsupported key type %T", public)
+	}
+
+	jwk.KID = thumbprint(jwk)
+	jwk.Alg = method.Alg()
+	jwk.Use = "sig"
+	if _, ok := m.keys[jwk.KID]; !ok {
+		m.keys[jwk.KID] = verificationKey{method: method, key: public}
+		m.jwks = append(m.jwks, jwk)
+	}
+	return jwk.KID, method, nil
+}
+
+// thumbprint computes the RFC 7638 thumbprint of a public key
+func thumbprint(jwk JWK) string {
+	// Required members only, in lexicographic order
+	var canonical []byte
+	if jwk.Kty == "RSA" {
+		canonical, _ = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N})
+	} else {
+		canonical, _ = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X})
+	}
+	sum := sha256.Sum256(canonical)
+	return b64(sum[:])
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parsePrivateKey reads a PKCS#1 or PKCS#8 RSA key or a PKCS#8 Ed25519 key
+func parsePrivateKey(data []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", key)
+}
+
+// parsePublicKey reads a PKIX public key. A private key is accepted too, so
+// the retired signing key file can be listed as it is.
+func parsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	private, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return private.(crypto.Signer).Public(), nil
+}
+
+// Keys used by GenerateJWT and ParseAccessToken
+var (
+	keysMu sync.RWMutex
+	keys   *KeyManager
+)
+
+// SetKeyManager sets the keys used to sign and verify access tokens
+func SetKeyManager(m *KeyManager) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keys = m
+}
+
+// Keys returns the keys used to sign and verify access tokens, loading
+// them from the environment on first use
+func Keys() (*KeyManager, error) {
+	keysMu.RLock()
+	m := keys
+	keysMu.RUnlock()
+	if m != nil {
+		return m, nil
+	}
+
+	m, err := LoadKeyManager()
+	if err != nil {
+		return nil, err
+	}
+	SetKeyManager(m)
+	return m, nil
+}
@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func rsaKeyPEM(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func ed25519KeyPEM(t *testing.T) []byte {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to encode Ed25519 key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// TestKeyRotation tests that tokens verify against the key named by their
+// kid, including retired keys kept for rotation
+func TestKeyRotation(t *testing.T) {
+	oldPEM, newPEM, otherPEM := rsaKeyPEM(t), ed25519KeyPEM(t), rsaKeyPEM(t)
+
+	oldKeys, err := NewKeyManager(oldPEM)
+	if err != nil {
+		t.Fatalf("Failed to load old key: %v", err)
+	}
+	newKeys, err := NewKeyManager(newPEM, oldPEM)
+	if err != nil {
+		t.Fatalf("Failed to load new key: %v", err)
+	}
+	otherKeys, err := NewKeyManager(otherPEM)
+	if err != nil {
+		t.Fatalf("Failed to load other key: %v", err)
+	}
+
+	sign := func(m *KeyManager) string {
+		token, err := m.Sign(jwt.MapClaims{"user_id": "user-1"})
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		expectedValid bool
+	}{
+		// ✅ Signed with the current key (EdDSA)
+		{"Current key", sign(newKeys), true},
+		// ✅ Signed with the retired key (RS256) before rotation
+		{"Retired key", sign(oldKeys), true},
+		// ❌ Signed with a key we never trusted
+		{"Unknown key", sign(otherKeys), false},
+		// ❌ Unsigned
+		{"No signature", func() string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"user_id": "user-1"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return token
+		}(), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			token, err := newKeys.Parse(tc.token)
+			valid := err == nil && token.Valid
+			if valid != tc.expectedValid {
+				t.Errorf("Expected valid=%v, got %v (%v)", tc.expectedValid, valid, err)
+			}
+		})
+	}
+
+	// Both keys are published, each under its kid
+	jwks := newKeys.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Alg != "EdDSA" || jwks.Keys[1].Alg != "RS256" {
+		t.Fatalf("Expected the EdDSA and RS256 keys in the JWKS, got %+v", jwks.Keys)
+	}
+	if oldKeys.JWKS().Keys[0].KID != jwks.Keys[1].KID {
+		t.Error("Expected a key to keep its kid across key managers")
+	}
+}
+
+// TestAlgorithmConfusion tests that a token cannot pick a different
+// algorithm than its key, e.g. HS256 with the public key as the secret
+func TestAlgorithmConfusion(t *testing.T) {
+	keys, err := NewKeyManager(rsaKeyPEM(t))
+	if err != nil {
+		t.Fatalf("Failed to load key: %v", err)
+	}
+	jwk := keys.JWKS().Keys[0]
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "admin"})
+	token.Header["kid"] = jwk.KID
+	forged, _ := token.SignedString([]byte(jwk.N))
+
+	if _, err := keys.Parse(forged); err == nil {
+		t.Error("Expected a token with the wrong algorithm to be rejected")
+	}
+}
+
+// TestLoadKeyManager tests that the public default secret is refused
+// outside development mode
+func TestLoadKeyManager(t *testing.T) {
+	tests := []struct {
+		name        string
+		secret      string
+		devMode     string
+		expectedErr error
+	}{
+		// ❌ No secret in production
+		{"Unset secret", "", "", ErrDefaultSecret},
+		// ❌ The default secret spelled out
+		{"Default secret", "default_secret", "false", ErrDefaultSecret},
+		// ✅ Development mode may use the default
+		{"Development mode", "", "true", nil},
+		// ✅ A real secret
+		{"Configured secret", "s3cr3t-value", "", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			t.Setenv("JWT_SIGNING_KEY_FILE", "")
+			t.Setenv("JWT_SECRET", tc.secret)
+			t.Setenv("DEV_MODE", tc.devMode)
+			if _, err := LoadKeyManager(); !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
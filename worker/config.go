@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/config"
+)
+
+// Config controls how a worker consumes the transaction queue.
+type Config struct {
+	QueueName   string
+	Concurrency int // Number of messages processed in parallel
+	Prefetch    int // Unacked messages the broker may push to this consumer (QoS)
+	RetryPolicy RetryPolicy
+
+	SnapshotInterval time.Duration // Spacing of balance snapshots; 0 disables them
+}
+
+// LoadConfig reads the worker configuration from the environment. The API
+// reads the same variables so both declare an identical queue topology.
+func LoadConfig() Config {
+	cfg := Config{
+		QueueName:   config.GetEnv("WORKER_QUEUE", "transactions"),
+		Concurrency: config.GetEnvInt("WORKER_CONCURRENCY", 4),
+		Prefetch:    config.GetEnvInt("WORKER_PREFETCH", 10),
+		RetryPolicy: RetryPolicy{
+			MaxRetries: config.GetEnvInt("WORKER_MAX_RETRIES", DefaultRetryPolicy.MaxRetries),
+			BaseDelay:  config.GetEnvDuration("WORKER_RETRY_BASE_DELAY", DefaultRetryPolicy.BaseDelay),
+		},
+		SnapshotInterval: config.GetEnvDuration("WORKER_SNAPSHOT_INTERVAL", 24*time.Hour),
+	}
+
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Prefetch < cfg.Concurrency {
+		cfg.Prefetch = cfg.Concurrency // Otherwise some goroutines would always sit idle
+	}
+	return cfg
+}
@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/utils"
+	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// HealthHandler reports whether the worker's dependencies are reachable. It
+// returns 503 once draining is set so orchestrators stop routing to it.
+func HealthHandler(postgresDB *gorm.DB, mongoDB *mongo.Database, rabbitMQConn *amqp.Connection, draining *atomic.Bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		checks := map[string]string{
+			"postgres": "ok",
+			"mongodb":  "ok",
+			"rabbitmq": "ok",
+		}
+		healthy := true
+
+		if sqlDB, err := postgresDB.DB(); err != nil || sqlDB.PingContext(ctx) != nil {
+			checks["postgres"] = "unreachable"
+			healthy = false
+		}
+		if err := mongoDB.Client().Ping(ctx, nil); err != nil {
+			checks["mongodb"] = "unreachable"
+			healthy = false
+		}
+		if rabbitMQConn == nil || rabbitMQConn.IsClosed() {
+			checks["rabbitmq"] = "closed"
+			healthy = false
+		}
+
+		if draining.Load() {
+			utils.SendResponse(w, http.StatusServiceUnavailable, false, "Worker is shutting down", checks, "")
+			return
+		}
+		if !healthy {
+			utils.SendResponse(w, http.StatusServiceUnavailable, false, "Worker is unhealthy", checks, "")
+			return
+		}
+		utils.SendResponse(w, http.StatusOK, true, "Worker is healthy", checks, "")
+	}
+}
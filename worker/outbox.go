@@ -0,0 +1,216 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EnqueueTransaction writes a transaction message to the outbox. Call it with
+// the same *gorm.DB transaction as the ledger change so that the message is
+// published if and only if that change commits.
+func EnqueueTransaction(tx *gorm.DB, transaction models.Transaction, queueName string) error {
+	if err := transaction.Validate(); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(transaction)
+	if err != nil {
+		return err
+	}
+
+	messageID := transaction.IdempotencyKey
+	if messageID == "" {
+		messageID = uuid.New().String()
+	}
+
+	return tx.Create(&models.OutboxMessage{
+		RoutingKey:  queueName,
+		MessageID:   messageID,
+		ContentType: "application/json",
+		Payload:     body,
+	}).Error
+}
+
+// OutboxRelay publishes committed outbox rows to RabbitMQ and marks them sent
+// once the broker confirms them.
+type OutboxRelay struct {
+	DB             *gorm.DB
+	Publisher      RabbitMQPublisher
+	Confirms       <-chan amqp.Confirmation // nil when the publisher cannot confirm (e.g. in tests)
+	PollInterval   time.Duration
+	BatchSize      int
+	ConfirmTimeout time.Duration
+
+	deliveryTag uint64 // tag of the last message published on the confirm channel
+}
+
+// NewOutboxRelay creates a relay. confirms must come from NotifyPublish on a
+// channel that has been put into confirm mode and is used only by this relay.
+func NewOutboxRelay(db *gorm.DB, publisher RabbitMQPublisher, confirms <-chan amqp.Confirmation) *OutboxRelay {
+	return &OutboxRelay{
+		DB:             db,
+		Publisher:      publisher,
+		Confirms:       confirms,
+		PollInterval:   time.Second,
+		BatchSize:      100,
+		ConfirmTimeout: 5 * time.Second,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	log.Println("[Outbox] Starting outbox relay...")
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.RelayBatch(); err != nil {
+			log.Println("[Outbox] Relay failed:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("[Outbox] Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayBatch publishes up to BatchSize pending rows in order and returns how
+// many were confirmed. It stops at the first failure so ordering is kept.
+// Rows are claimed in a short transaction and published outside it, so a
+// slow broker never holds database locks.
+func (r *OutboxRelay) RelayBatch() (int, error) {
+	token := uuid.New().String()
+	claimedAt := time.Now()
+	pending, err := r.claim(token, claimedAt)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range pending {
+		msg := &pending[i]
+		if time.Since(claimedAt) > r.claimTTL()-r.ConfirmTimeout {
+			break // Leave the rest for a fresh claim before this one expires
+		}
+
+		if err := r.publish(msg); err != nil {
+			r.DB.Model(msg).Where("claim_token = ?", token).Updates(map[string]interface{}{
+				"attempts":   msg.Attempts + 1,
+				"last_error": err.Error(),
+			})
+			break // Retry the rest on the next tick
+		}
+
+		now := time.Now()
+		if err := r.DB.Model(msg).Where("claim_token = ?", token).Update("sent_at", now).Error; err != nil {
+			r.release(token)
+			return sent, err
+		}
+		sent++
+	}
+
+	r.release(token)
+	return sent, nil
+}
+
+// claimTTL is how long a relay may hold claimed rows. It covers a broker
+// that times out on every message of a batch, plus some slack.
+func (r *OutboxRelay) claimTTL() time.Duration {
+	return time.Duration(r.BatchSize)*r.ConfirmTimeout + time.Minute
+}
+
+// claim marks up to BatchSize pending rows as in flight for this relay run.
+// Rows claimed by a relay that died are claimed again once claimTTL passes.
+func (r *OutboxRelay) claim(token string, now time.Time) ([]models.OutboxMessage, error) {
+	var pending []models.OutboxMessage
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED lets several relays claim rows at the same time
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL").
+			Where("claimed_at IS NULL OR claimed_at < ?", now.Add(-r.claimTTL())).
+			Order("id").
+			Limit(r.BatchSize).
+			Find(&pending).Error
+		if err != nil || len(pending) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(pending))
+		for i, msg := range pending {
+			ids[i] = msg.ID
+		}
+		return tx.Model(&models.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{"claim_token": token, "claimed_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// release hands the unsent rows of a relay run back to the outbox
+func (r *OutboxRelay) release(token string) {
+	err := r.DB.Model(&models.OutboxMessage{}).
+		Where("claim_token = ? AND sent_at IS NULL", token).
+		Updates(map[string]interface{}{"claim_token": "", "claimed_at": nil}).Error
+	if err != nil {
+		log.Println("[Outbox] Failed to release claimed messages:", err)
+	}
+}
+
+// publish sends one outbox row and waits for the broker's confirmation.
+func (r *OutboxRelay) publish(msg *models.OutboxMessage) error {
+	err := r.Publisher.Publish(
+		msg.Exchange,
+		msg.RoutingKey,
+		false,
+		false,
+		amqp.Publishing{
+			ContentType:  msg.ContentType,
+			MessageId:    msg.MessageID,
+			DeliveryMode: amqp.Persistent,
+			Body:         msg.Payload,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if r.Confirms == nil {
+		return nil
+	}
+	r.deliveryTag++
+
+	timeout := time.After(r.ConfirmTimeout)
+	for {
+		select {
+		case confirm, ok := <-r.Confirms:
+			if !ok {
+				return errors.New("confirm channel closed")
+			}
+			if confirm.DeliveryTag < r.deliveryTag {
+				continue // Late confirmation of an earlier, timed-out publish
+			}
+			if !confirm.Ack {
+				return fmt.Errorf("broker rejected message %s", msg.MessageID)
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for broker confirmation of %s", msg.MessageID)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/streadway/amqp"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeBroker records published messages and confirms each one on a channel
+type fakeBroker struct {
+	published []amqp.Publishing
+	confirms  chan amqp.Confirmation
+	ack       bool
+}
+
+func (b *fakeBroker) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	b.published = append(b.published, msg)
+	b.confirms <- amqp.Confirmation{DeliveryTag: uint64(len(b.published)), Ack: b.ack}
+	return nil
+}
+
+// setupOutboxDB opens an in-memory database with the outbox table migrated
+func setupOutboxDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.OutboxMessage{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+func enqueueDeposit(t *testing.T, db *gorm.DB, key string) {
+	txn := models.Transaction{
+		Type:           "deposit",
+		AccountNumber:  "1111111111",
+		Amount:         models.NewMoney(1000, "USD"),
+		IdempotencyKey: key,
+	}
+	if err := EnqueueTransaction(db, txn, "transactions"); err != nil {
+		t.Fatalf("Failed to enqueue transaction: %v", err)
+	}
+}
+
+// TestOutboxRelayMarksConfirmedMessagesSent tests the happy path of the relay
+func TestOutboxRelayMarksConfirmedMessagesSent(t *testing.T) {
+	db := setupOutboxDB(t)
+	enqueueDeposit(t, db, "key-1")
+	enqueueDeposit(t, db, "key-2")
+
+	broker := &fakeBroker{confirms: make(chan amqp.Confirmation, 10), ack: true}
+	relay := NewOutboxRelay(db, broker, broker.confirms)
+
+	sent, err := relay.RelayBatch()
+	if err != nil {
+		t.Fatalf("Relay failed: %v", err)
+	}
+	if sent != 2 {
+		t.Fatalf("Expected 2 messages sent, got %d", sent)
+	}
+	if broker.published[0].MessageId != "key-1" || broker.published[1].MessageId != "key-2" {
+		t.Errorf("Messages published out of order or without IDs: %+v", broker.published)
+	}
+
+	var pending int64
+	db.Model(&models.OutboxMessage{}).Where("sent_at IS NULL").Count(&pending)
+	if pending != 0 {
+		t.Errorf("Expected no pending messages, got %d", pending)
+	}
+
+	// A second pass has nothing left to send
+	if sent, _ := relay.RelayBatch(); sent != 0 {
+		t.Errorf("Expected already-sent messages to be skipped, got %d sent", sent)
+	}
+}
+
+// TestOutboxRelayKeepsRejectedMessages tests that nacked messages are retried later
+func TestOutboxRelayKeepsRejectedMessages(t *testing.T) {
+	db := setupOutboxDB(t)
+	enqueueDeposit(t, db, "key-1")
+
+	broker := &fakeBroker{confirms: make(chan amqp.Confirmation, 10), ack: false}
+	relay := NewOutboxRelay(db, broker, broker.confirms)
+	relay.ConfirmTimeout = time.Second
+
+	if sent, _ := relay.RelayBatch(); sent != 0 {
+		t.Fatalf("Expected nothing confirmed, got %d sent", sent)
+	}
+
+	var msg models.OutboxMessage
+	db.First(&msg)
+	if msg.SentAt != nil {
+		t.Error("Rejected message must not be marked sent")
+	}
+	if msg.Attempts != 1 || msg.LastError == "" {
+		t.Errorf("Expected the failed attempt to be recorded, got attempts=%d error=%q", msg.Attempts, msg.LastError)
+	}
+}
+
+// TestOutboxRelayClaims tests that rows in flight with one relay are not
+// published by another until the claim expires
+func TestOutboxRelayClaims(t *testing.T) {
+	db := setupOutboxDB(t)
+	enqueueDeposit(t, db, "key-1")
+	enqueueDeposit(t, db, "key-2")
+
+	broker := &fakeBroker{confirms: make(chan amqp.Confirmation, 10), ack: true}
+	relay := NewOutboxRelay(db, broker, broker.confirms)
+
+	// Another relay claimed the rows and is still publishing them
+	claimed, err := relay.claim("other-relay", time.Now())
+	if err != nil || len(claimed) != 2 {
+		t.Fatalf("Expected to claim 2 messages, got %d (%v)", len(claimed), err)
+	}
+	if sent, err := relay.RelayBatch(); err != nil || sent != 0 {
+		t.Fatalf("Expected claimed messages to be skipped, got %d sent (%v)", sent, err)
+	}
+
+	// That relay died; once its claim expires the rows are published
+	db.Model(&models.OutboxMessage{}).Where("claim_token = ?", "other-relay").Update("claimed_at", time.Now().Add(-2*relay.claimTTL()))
+	if sent, err := relay.RelayBatch(); err != nil || sent != 2 {
+		t.Fatalf("Expected 2 messages sent after the claim expired, got %d (%v)", sent, err)
+	}
+
+	// A failed publish hands the row back for the next run
+	enqueueDeposit(t, db, "key-3")
+	broker.ack = false
+	relay.ConfirmTimeout = time.Second
+	relay.RelayBatch()
+	var msg models.OutboxMessage
+	db.Where("message_id = ?", "key-3").First(&msg)
+	if msg.SentAt != nil || msg.ClaimedAt != nil || msg.ClaimToken != "" {
+		t.Errorf("Expected the failed message to be released unsent, got %+v", msg)
+	}
+}
@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Message headers used by the retry and dead-letter flow
+const (
+	RetryCountHeader    = "x-retry-count"
+	FailureReasonHeader = "x-failure-reason"
+	OriginalQueueHeader = "x-original-queue"
+	FailedAtHeader      = "x-failed-at"
+)
+
+// PermanentError marks a failure that will fail again on every retry, such
+// as insufficient funds or a malformed message. Such messages go straight to
+// the dead-letter queue.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent wraps err as a PermanentError.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err (or anything it wraps) is permanent.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// RetryPolicy bounds how often a transiently failing message is retried and
+// how long to wait between attempts.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+}
+
+// DefaultRetryPolicy retries five times after 1s, 2s, 4s, 8s and 16s.
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 5, BaseDelay: time.Second}
+
+// Delay returns the backoff before the given retry attempt (1-based).
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	return p.BaseDelay * time.Duration(1<<uint(attempt-1))
+}
+
+// retryQueueName is the TTL queue holding messages waiting out a delay. The
+// delay is part of the name because RabbitMQ refuses to redeclare a queue
+// with a different TTL, so changing the retry policy declares new queues.
+func retryQueueName(queueName string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%d", queueName, delay.Milliseconds())
+}
+
+// deadLetterExchange receives messages that will not be retried
+func deadLetterExchange(queueName string) string {
+	return queueName + ".dlx"
+}
+
+// deadLetterQueueName is bound to the dead-letter exchange for inspection
+func deadLetterQueueName(queueName string) string {
+	return queueName + ".dlq"
+}
+
+// DeclareTopology declares the queues used for transaction processing: the
+// work queue, one TTL retry queue per retry delay that dead-letters back into
+// the work queue, and a dead-letter exchange and queue. Both the API (which
+// publishes) and the worker (which consumes) call it so that messages are
+// never routed to a queue that does not exist yet.
+func DeclareTopology(rabbitMQChannel *amqp.Channel, queueName string, policy RetryPolicy) error {
+	_, err := rabbitMQChannel.QueueDeclare(
+		queueName, // Queue name
+		true,      // Durable
+		false,     // Auto delete
+		false,     // Exclusive
+		false,     // No-wait
+		nil,       // Arguments
+	)
+	if err != nil {
+		return err
+	}
+
+	// Retry queues have no consumers; expired messages return to the work queue
+	for attempt := 1; attempt <= policy.MaxRetries; attempt++ {
+		_, err := rabbitMQChannel.QueueDeclare(retryQueueName(queueName, policy.Delay(attempt)), true, false, false, false, amqp.Table{
+			"x-message-ttl":             policy.Delay(attempt).Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queueName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := rabbitMQChannel.ExchangeDeclare(deadLetterExchange(queueName), "direct", true, false, false, false, nil); err != nil {
+		return err
+	}
+	if _, err := rabbitMQChannel.QueueDeclare(deadLetterQueueName(queueName), true, false, false, false, nil); err != nil {
+		return err
+	}
+	return rabbitMQChannel.QueueBind(deadLetterQueueName(queueName), queueName, deadLetterExchange(queueName), false, nil)
+}
+
+// handleFailure routes a failed message: permanent failures and messages out
+// of retries go to the dead-letter exchange, everything else to the next
+// retry queue. The original delivery is acked once the copy is published.
+// It reports whether the message was dead-lettered.
+func handleFailure(publisher RabbitMQPublisher, msg amqp.Delivery, queueName string, policy RetryPolicy, cause error) bool {
+	retries := retryCount(msg.Headers)
+	deadLettered := IsPermanent(cause) || retries >= policy.MaxRetries
+
+	var err error
+	if deadLettered {
+		log.Printf("[Worker] Dead-lettering message %s after %d retries: %v", msg.MessageId, retries, cause)
+		headers := copyHeaders(msg.Headers)
+		headers[FailureReasonHeader] = cause.Error()
+		headers[OriginalQueueHeader] = queueName
+		headers[FailedAtHeader] = time.Now().UTC().Format(time.RFC3339)
+		err = republish(publisher, msg, deadLetterExchange(queueName), queueName, headers)
+	} else {
+		log.Printf("[Worker] Retrying message %s in %s (attempt %d/%d): %v",
+			msg.MessageId, policy.Delay(retries+1), retries+1, policy.MaxRetries, cause)
+		headers := copyHeaders(msg.Headers)
+		headers[RetryCountHeader] = int32(retries + 1)
+		err = republish(publisher, msg, "", retryQueueName(queueName, policy.Delay(retries+1)), headers)
+	}
+
+	if err != nil {
+		log.Println("[Worker] Failed to route failed message, requeueing:", err)
+		msg.Nack(false, true)
+		return false
+	}
+	msg.Ack(false)
+	return deadLettered
+}
+
+// republish sends a copy of the delivery with new headers.
+func republish(publisher RabbitMQPublisher, msg amqp.Delivery, exchange, key string, headers amqp.Table) error {
+	return publisher.Publish(exchange, key, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  msg.ContentType,
+		MessageId:    msg.MessageId,
+		DeliveryMode: amqp.Persistent,
+		Body:         msg.Body,
+	})
+}
+
+// retryCount reads the number of retries already attempted from the headers.
+func retryCount(headers amqp.Table) int {
+	switch v := headers[RetryCountHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+func copyHeaders(headers amqp.Table) amqp.Table {
+	copied := amqp.Table{}
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
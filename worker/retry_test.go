@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// fakeAcknowledger records how a delivery was settled
+type fakeAcknowledger struct {
+	acked, nacked bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error { a.acked = true; return nil }
+func (a *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked = true
+	return nil
+}
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error { a.nacked = true; return nil }
+
+// TestHandleFailure tests routing of failed messages to retry or dead-letter queues
+func TestHandleFailure(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Second}
+
+	tests := []struct {
+		name          string
+		retries       int
+		cause         error
+		expectedKey   string
+		expectedRetry int32
+		deadLettered  bool
+	}{
+		// 🔁 Transient failure goes to the first retry queue
+		{"Transient failure", 0, errors.New("connection reset"), "transactions.retry.1000", 1, false},
+		// 🔁 Later attempts use later (slower) retry queues
+		{"Second transient failure", 1, errors.New("connection reset"), "transactions.retry.2000", 2, false},
+		// ☠️ Permanent failure is dead-lettered immediately
+		{"Permanent failure", 0, Permanent(errors.New("insufficient funds for withdrawal")), "transactions", 0, true},
+		// ☠️ Retries exhausted
+		{"Retries exhausted", 3, errors.New("connection reset"), "transactions", 3, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fmt.Println("Running test:", tc.name) // Print log
+			broker := &fakeBroker{confirms: make(chan amqp.Confirmation, 1), ack: true}
+			ack := &fakeAcknowledger{}
+			msg := amqp.Delivery{
+				Acknowledger: ack,
+				MessageId:    "msg-1",
+				Body:         []byte(`{}`),
+				Headers:      amqp.Table{},
+			}
+			if tc.retries > 0 {
+				msg.Headers[RetryCountHeader] = int32(tc.retries)
+			}
+
+			var routedTo string
+			publisher := publisherFunc(func(exchange, key string, msg amqp.Publishing) error {
+				routedTo = exchange + "/" + key
+				return broker.Publish(exchange, key, false, false, msg)
+			})
+			handleFailure(publisher, msg, "transactions", policy, tc.cause)
+
+			if !ack.acked || ack.nacked {
+				t.Fatalf("Expected original delivery to be acked, got acked=%v nacked=%v", ack.acked, ack.nacked)
+			}
+			if len(broker.published) != 1 {
+				t.Fatalf("Expected exactly one republished copy, got %d", len(broker.published))
+			}
+
+			republished := broker.published[0]
+			if tc.deadLettered {
+				if routedTo != "transactions.dlx/transactions" {
+					t.Errorf("Expected dead-letter exchange, got %s", routedTo)
+				}
+				if republished.Headers[FailureReasonHeader] != tc.cause.Error() {
+					t.Errorf("Expected failure reason header, got %v", republished.Headers[FailureReasonHeader])
+				}
+			} else {
+				if routedTo != "/"+tc.expectedKey {
+					t.Errorf("Expected retry queue %s, got %s", tc.expectedKey, routedTo)
+				}
+				if republished.Headers[RetryCountHeader] != tc.expectedRetry {
+					t.Errorf("Expected retry count %d, got %v", tc.expectedRetry, republished.Headers[RetryCountHeader])
+				}
+			}
+		})
+	}
+}
+
+// TestRetryPolicyDelay tests exponential backoff
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 4, BaseDelay: 500 * time.Millisecond}
+	expected := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second}
+	for i, want := range expected {
+		if got := policy.Delay(i + 1); got != want {
+			t.Errorf("Delay of attempt %d: expected %s, got %s", i+1, want, got)
+		}
+	}
+}
+
+// publisherFunc adapts a function to the RabbitMQPublisher interface
+type publisherFunc func(exchange, key string, msg amqp.Publishing) error
+
+func (f publisherFunc) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	return f(exchange, key, msg)
+}
@@ -0,0 +1,42 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/ledger"
+	"gorm.io/gorm"
+)
+
+// RunBalanceSnapshots snapshots every account balance at each interval
+// boundary (e.g. midnight UTC for 24h) until ctx is cancelled. Point-in-time
+// balance queries start from these snapshots.
+func RunBalanceSnapshots(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	log.Println("[Snapshots] Starting balance snapshots every", interval)
+
+	// Check more often than the interval so a boundary is not missed by much
+	ticker := time.NewTicker(ledger.SnapshotLag)
+	defer ticker.Stop()
+
+	var last time.Time
+	for {
+		if boundary := ledger.SnapshotBoundary(time.Now(), interval); !boundary.Equal(last) {
+			written, err := ledger.TakeSnapshots(db, boundary)
+			if err != nil {
+				log.Println("[Snapshots] Failed to take snapshots:", err)
+			} else {
+				last = boundary
+				if written > 0 {
+					log.Printf("[Snapshots] Recorded %d balances as of %s", written, boundary.Format(time.RFC3339))
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ashil-poojary/banking-ledger-service/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrStatusConflict is returned when a transaction's status keeps changing
+// underneath a transition.
+var ErrStatusConflict = errors.New("transaction status changed concurrently")
+
+// TransitionStatus moves a stored transaction to a new status and records the
+// change in its history. The stored document is read first and only updated
+// while its status is unchanged, so concurrent writers cannot both apply
+// conflicting transitions. A transaction already in the target status is
+// returned unchanged, which makes redelivered messages harmless. Extra fields
+// (e.g. the journal reference) are set together with the status.
+func TransitionStatus(mongoDB *mongo.Database, id primitive.ObjectID, to, reason string, fields bson.M) (*models.Transaction, error) {
+	collection := mongoDB.Collection("transactions")
+
+	for attempt := 0; attempt < 3; attempt++ {
+		var transaction models.Transaction
+		if err := collection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&transaction); err != nil {
+			return nil, err
+		}
+		if transaction.Status == to {
+			return &transaction, nil
+		}
+
+		from := transaction.Status
+		change, err := transaction.TransitionTo(to, reason, time.Now())
+		if err != nil {
+			return &transaction, err
+		}
+
+		set := bson.M{
+			"status":     transaction.Status,
+			"updated_at": transaction.UpdatedAt,
+		}
+		if to == models.StatusFailed {
+			set["failure_reason"] = reason
+		}
+		for k, v := range fields {
+			set[k] = v
+		}
+
+		result, err := collection.UpdateOne(context.TODO(),
+			bson.M{"_id": id, "status": from},
+			bson.M{"$set": set, "$push": bson.M{"status_history": change}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 1 {
+			return &transaction, nil
+		}
+		// Someone else moved the transaction first; re-read and try again
+	}
+
+	return nil, ErrStatusConflict
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
 )
 
@@ -26,6 +27,12 @@ func PublishTransaction(transaction models.Transaction, publisher RabbitMQPublis
 		return err
 	}
 
+	// Every message carries an ID so the worker can detect redeliveries
+	messageID := transaction.IdempotencyKey
+	if messageID == "" {
+		messageID = uuid.New().String()
+	}
+
 	err = publisher.Publish(
 		"",
 		queueName,
@@ -33,6 +40,7 @@ func PublishTransaction(transaction models.Transaction, publisher RabbitMQPublis
 		false,
 		amqp.Publishing{
 			ContentType: "application/json",
+			MessageId:   messageID,
 			Body:        body,
 		},
 	)
@@ -4,60 +4,117 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"github.com/ashil-poojary/banking-ledger-service/ledger"
 	"github.com/ashil-poojary/banking-ledger-service/models"
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// ProcessTransactions listens for transaction messages and processes them
-func ProcessTransactions(queueName string, postgresDB *gorm.DB, mongoDB *mongo.Database, rabbitMQChannel *amqp.Channel) {
-	log.Println("[Worker] Starting transaction processing...")
+// ProcessTransactions consumes transaction messages until ctx is cancelled.
+// Messages are processed by cfg.Concurrency goroutines; on cancellation the
+// consumer stops receiving and waits for in-flight messages to finish.
+func ProcessTransactions(ctx context.Context, cfg Config, postgresDB *gorm.DB, mongoDB *mongo.Database, rabbitMQChannel *amqp.Channel) error {
+	log.Printf("[Worker] Starting transaction processing (concurrency=%d, prefetch=%d)...", cfg.Concurrency, cfg.Prefetch)
 
 	// Ensure the queue exists before consuming
-	_, err := rabbitMQChannel.QueueDeclare(
-		queueName, // Queue name
-		true,      // Durable
-		false,     // Auto delete
-		false,     // Exclusive
-		false,     // No-wait
-		nil,       // Arguments
-	)
-	if err != nil {
-		log.Fatalf("[Worker] Failed to declare queue: %v", err)
+	if err := DeclareTopology(rabbitMQChannel, cfg.QueueName, cfg.RetryPolicy); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
 	}
 
-	msgs, err := rabbitMQChannel.Consume(queueName, "", false, false, false, false, nil)
-	if err != nil {
-		log.Fatalf("[Worker] Failed to register consumer: %v", err)
+	// Limit unacked deliveries so one worker cannot hoard the queue
+	if err := rabbitMQChannel.Qos(cfg.Prefetch, 0, false); err != nil {
+		return fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	for msg := range msgs {
-		var transaction models.Transaction
+	consumerTag := "worker-" + uuid.New().String()
+	msgs, err := rabbitMQChannel.Consume(cfg.QueueName, consumerTag, false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to register consumer: %w", err)
+	}
 
-		if err := json.Unmarshal(msg.Body, &transaction); err != nil {
-			log.Println("[Worker] Failed to unmarshal transaction:", err)
-			msg.Reject(false) // Permanently reject invalid messages
-			continue
+	// Stop receiving new messages on shutdown; msgs is closed once cancelled
+	go func() {
+		<-ctx.Done()
+		log.Println("[Worker] Shutting down, finishing in-flight messages...")
+		if err := rabbitMQChannel.Cancel(consumerTag, false); err != nil {
+			log.Println("[Worker] Failed to cancel consumer:", err)
 		}
+	}()
 
-		// 🔹 **PostgreSQL Transaction**
-		err := processTransaction(postgresDB, mongoDB, &transaction)
-		if err != nil {
-			log.Println("[Worker] Transaction processing failed:", err)
-			msg.Nack(false, true) // Retry message in RabbitMQ
-			continue
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range msgs {
+				handleMessage(msg, cfg, postgresDB, mongoDB, rabbitMQChannel)
+			}
+		}()
+	}
+	wg.Wait()
+
+	log.Println("[Worker] Transaction processing stopped")
+	return nil
+}
+
+// handleMessage processes one delivery and settles it.
+func handleMessage(msg amqp.Delivery, cfg Config, postgresDB *gorm.DB, mongoDB *mongo.Database, publisher RabbitMQPublisher) {
+	var transaction models.Transaction
+
+	if err := json.Unmarshal(msg.Body, &transaction); err != nil {
+		log.Println("[Worker] Failed to unmarshal transaction:", err)
+		handleFailure(publisher, msg, cfg.QueueName, cfg.RetryPolicy, Permanent(err)) // Invalid messages are never retried
+		return
+	}
+
+	// 🔹 **Identify the message for exactly-once processing**
+	messageID := msg.MessageId
+	if messageID == "" {
+		messageID = transaction.IdempotencyKey
+	}
+
+	// 🔹 **Move the pending record created by the API to processing**
+	if !transaction.ID.IsZero() && transaction.Type != "transfer" {
+		stored, err := TransitionStatus(mongoDB, transaction.ID, models.StatusProcessing, "", nil)
+		if errors.Is(err, models.ErrIllegalTransition) && stored.Status != models.StatusCompleted {
+			log.Printf("[Worker] Skipping transaction %s: it is already %s", transaction.ID.Hex(), stored.Status)
+			msg.Ack(false)
+			return
 		}
+		if err != nil && !errors.Is(err, models.ErrIllegalTransition) {
+			log.Println("[Worker] Failed to mark transaction as processing:", err)
+			handleFailure(publisher, msg, cfg.QueueName, cfg.RetryPolicy, err)
+			return
+		}
+	}
 
-		msg.Ack(false) // Acknowledge successful processing
+	// 🔹 **PostgreSQL Transaction**
+	err := processTransaction(postgresDB, mongoDB, cfg.QueueName, messageID, &transaction)
+	if err != nil {
+		log.Println("[Worker] Transaction processing failed:", err)
+		if handleFailure(publisher, msg, cfg.QueueName, cfg.RetryPolicy, err) { // Retry later or dead-letter
+			markFailed(mongoDB, &transaction, err)
+		}
+		return
 	}
+
+	msg.Ack(false) // Acknowledge successful processing
 }
 
-// processTransaction handles transaction logic within a PostgreSQL transaction
-func processTransaction(postgresDB *gorm.DB, mongoDB *mongo.Database, transaction *models.Transaction) error {
+// processTransaction applies a transaction exactly once. The message ID is
+// recorded in processed_messages inside the same PostgreSQL transaction as
+// the journal entry, so a redelivered message only repeats the MongoDB log.
+func processTransaction(postgresDB *gorm.DB, mongoDB *mongo.Database, queueName, messageID string, transaction *models.Transaction) error {
 	tx := postgresDB.Begin() // Start transaction
 	defer func() {
 		if r := recover(); r != nil {
@@ -70,33 +127,51 @@ func processTransaction(postgresDB *gorm.DB, mongoDB *mongo.Database, transactio
 		return tx.Error
 	}
 
-	var balance float64
-
-	// 🔹 **Lock the row for update to prevent race conditions**
-	err := tx.Raw(`SELECT balance FROM accounts WHERE account_number = ? FOR UPDATE`, transaction.AccountNumber).Scan(&balance).Error
-	if err != nil {
+	if err := transaction.Validate(); err != nil {
 		tx.Rollback()
-		return err
+		return Permanent(err)
 	}
 
-	// 🔹 **Check sufficient funds for withdrawal**
-	if transaction.Type == "withdrawal" && balance < transaction.Amount {
-		tx.Rollback()
-		return errors.New("insufficient funds for withdrawal")
-	}
+	// 🔹 **Claim the message; a conflict means it was already applied**
+	processed := models.ProcessedMessage{MessageID: messageID, Queue: queueName}
+	if messageID == "" {
+		log.Println("[Worker] Message has no ID; redeliveries cannot be detected")
+	} else {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&processed)
+		if result.Error != nil {
+			tx.Rollback()
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			log.Println("[Worker] Message already processed, skipping ledger update:", messageID)
 
-	// 🔹 **Update balance**
-	newBalance := balance
-	if transaction.Type == "withdrawal" {
-		newBalance -= transaction.Amount
-	} else if transaction.Type == "deposit" {
-		newBalance += transaction.Amount
+			if err := postgresDB.Where("message_id = ?", messageID).First(&processed).Error; err != nil {
+				return err
+			}
+			if processed.JournalEntryID != "" {
+				transaction.Reference = processed.JournalEntryID
+			}
+			return logTransaction(mongoDB, transaction)
+		}
 	}
 
-	err = tx.Exec(`UPDATE accounts SET balance = ? WHERE account_number = ?`, newBalance, transaction.AccountNumber).Error
-	if err != nil {
-		tx.Rollback()
-		return err
+	// 🔹 **Transfers are posted by the API; only deposits and withdrawals are applied here**
+	if transaction.Type == "deposit" || transaction.Type == "withdrawal" {
+		entry, err := applyTransaction(tx, transaction)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		transaction.Reference = entry.ID
+
+		if messageID != "" {
+			err := tx.Model(&processed).Update("journal_entry_id", entry.ID).Error
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
 	}
 
 	// 🔹 **Commit PostgreSQL transaction**
@@ -104,18 +179,116 @@ func processTransaction(postgresDB *gorm.DB, mongoDB *mongo.Database, transactio
 		return err
 	}
 
-	// 🔹 **Insert transaction into MongoDB with retry**
-	transaction.Status = "completed"
+	return logTransaction(mongoDB, transaction)
+}
+
+// applyTransaction locks the account and posts a deposit or withdrawal entry.
+func applyTransaction(tx *gorm.DB, transaction *models.Transaction) (*models.JournalEntry, error) {
+	var account models.Account
+
+	// 🔹 **Lock the row for update to prevent race conditions**
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("account_number = ?", transaction.AccountNumber).
+		First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, Permanent(fmt.Errorf("account %s not found", transaction.AccountNumber))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 🔹 **Frozen and dormant accounts accept deposits but nothing may leave them; closed accounts accept nothing**
+	if transaction.Type == "withdrawal" && !account.AllowsDebits() {
+		return nil, Permanent(fmt.Errorf("account is %s", account.Status))
+	}
+	if transaction.Type == "deposit" && !account.AllowsCredits() {
+		return nil, Permanent(fmt.Errorf("account is %s", account.Status))
+	}
+
+	// 🔹 **Check sufficient funds for withdrawal**
+	cmp, err := account.Balance.Cmp(transaction.Amount)
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	if transaction.Type == "withdrawal" && cmp < 0 {
+		return nil, Permanent(errors.New("insufficient funds for withdrawal"))
+	}
+
+	// 🔹 **Post the journal entry (updates the cached balance)**
+	var entry *models.JournalEntry
+	if transaction.Type == "deposit" {
+		entry = models.NewDepositEntry(transaction.AccountNumber, transaction.Amount, transaction.Reference)
+	} else {
+		entry = models.NewWithdrawalEntry(transaction.AccountNumber, transaction.Amount, transaction.Reference)
+	}
+	if transaction.IdempotencyKey != "" {
+		entry.IdempotencyKey = &transaction.IdempotencyKey
+	}
+
+	if err := ledger.Post(tx, entry); err != nil {
+		return nil, err
+	}
+	err = ledger.Record(tx, entry, &models.LedgerTransaction{
+		AccountNumber: transaction.AccountNumber,
+		Amount:        transaction.Amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// logTransaction writes the completed transaction to MongoDB with retries.
+// Repeating it after a redelivery never creates a duplicate: API-created
+// records are moved to completed (a no-op if they already are), and other
+// messages are inserted once keyed by transaction ID or journal reference.
+func logTransaction(mongoDB *mongo.Database, transaction *models.Transaction) error {
 	retryCount := 3
 	for i := 0; i < retryCount; i++ {
-		_, err := mongoDB.Collection("transactions").InsertOne(context.TODO(), transaction)
+		err := saveTransaction(mongoDB, transaction)
 		if err == nil {
 			log.Println("[Worker] Transaction successfully logged in MongoDB")
 			return nil // Success
 		}
+		if errors.Is(err, models.ErrIllegalTransition) {
+			// The ledger change is committed; retrying cannot fix the record
+			log.Printf("[Worker] Transaction %s could not be marked completed: %v", transaction.ID.Hex(), err)
+			return nil
+		}
 		log.Printf("[Worker] MongoDB insertion failed (attempt %d/%d). Retrying in 2s... Error: %v", i+1, retryCount, err)
 		time.Sleep(2 * time.Second) // Backoff before retry
 	}
 
 	return errors.New("failed to insert transaction log into MongoDB after retries")
 }
+
+// saveTransaction performs a single attempt of logTransaction.
+func saveTransaction(mongoDB *mongo.Database, transaction *models.Transaction) error {
+	// Transfers arrive already completed and older messages carry no ID
+	if transaction.Type == "transfer" || transaction.ID.IsZero() {
+		filter := bson.M{"_id": transaction.ID}
+		if transaction.ID.IsZero() {
+			transaction.Status = models.StatusCompleted
+			transaction.UpdatedAt = time.Now()
+			filter = bson.M{"reference": transaction.Reference}
+		}
+		_, err := mongoDB.Collection("transactions").UpdateOne(context.TODO(), filter, bson.M{"$setOnInsert": transaction}, options.Update().SetUpsert(true))
+		return err
+	}
+
+	_, err := TransitionStatus(mongoDB, transaction.ID, models.StatusCompleted, "", bson.M{"reference": transaction.Reference})
+	return err
+}
+
+// markFailed records a transaction that will not be applied, so clients
+// polling its status see why. Transfers are posted before they are queued
+// and are never marked failed here.
+func markFailed(mongoDB *mongo.Database, transaction *models.Transaction, cause error) {
+	if transaction.ID.IsZero() || transaction.Type == "transfer" {
+		return
+	}
+
+	if _, err := TransitionStatus(mongoDB, transaction.ID, models.StatusFailed, cause.Error(), nil); err != nil {
+		log.Println("[Worker] Failed to mark transaction as failed:", err)
+	}
+}